@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterSetPermissionClaimSpec defines the desired state of a ClusterSetPermissionClaim.
+type ClusterSetPermissionClaimSpec struct {
+	// Selects the TargetClusters to claim permissions on.
+	// An empty selector selects all TargetClusters.
+	TargetClusterSelector metav1.LabelSelector `json:"targetClusterSelector"`
+	// Template for the PermissionClaims created for each selected TargetCluster.
+	// .targetClusterRef is set to the selected TargetCluster
+	// and the name of the TargetCluster is appended to .secretName.
+	// Expiry applies to the ClusterSetPermissionClaim as a whole:
+	// .expiresAfter counts from the creation of the ClusterSetPermissionClaim,
+	// PermissionClaims are not recreated once it has expired
+	// and .deleteOnExpiry deletes the ClusterSetPermissionClaim.
+	Template PermissionClaimSpec `json:"template"`
+	// Writes a single kubeconfig with a context per TargetCluster,
+	// in addition to the kubeconfig Secrets of the individual PermissionClaims.
+	Kubeconfig *ClusterSetKubeconfig `json:"kubeconfig,omitempty"`
+}
+
+// ClusterSetKubeconfig configures the kubeconfig combining the credentials for all TargetClusters.
+type ClusterSetKubeconfig struct {
+	// Name of the Secret to house the kubeconfig.
+	// Contexts are named after their TargetCluster.
+	SecretName string `json:"secretName"`
+	// Context to set as current-context.
+	// Defaults to the first TargetCluster in alphabetical order.
+	CurrentContext string `json:"currentContext,omitempty"`
+}
+
+// ClusterSetPermissionClaimStatus defines the observed state of a ClusterSetPermissionClaim.
+type ClusterSetPermissionClaimStatus struct {
+	// The most recent generation observed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions is a list of status conditions ths object is in.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// DEPRECATED: This field is not part of any API contract
+	// it will go away as soon as kubectl can print conditions!
+	// Human readable status - please use .Conditions from code
+	Phase PermissionClaimPhase `json:"phase,omitempty"`
+	// Point in time the claim expires at.
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Number of selected TargetClusters.
+	SelectedClusters int32 `json:"selectedClusters,omitempty"`
+	// Number of selected TargetClusters the claim is bound on.
+	BoundClusters int32 `json:"boundClusters,omitempty"`
+	// Contexts in the combined kubeconfig Secret.
+	// Only TargetClusters credentials have been issued for are included.
+	KubeconfigContexts []string `json:"kubeconfigContexts,omitempty"`
+	// Status of the claim on each selected TargetCluster.
+	TargetClusters []ClusterSetTargetClusterStatus `json:"targetClusters,omitempty"`
+}
+
+// ClusterSetTargetClusterStatus reports on the claim on a single TargetCluster.
+type ClusterSetTargetClusterStatus struct {
+	// Name of the TargetCluster.
+	Name string `json:"name"`
+	// Name of the PermissionClaim created for the TargetCluster.
+	ClaimName string `json:"claimName"`
+	// Name of the Secret containing the kubeconfig for the TargetCluster.
+	SecretName string `json:"secretName"`
+	// Phase of the PermissionClaim.
+	Phase PermissionClaimPhase `json:"phase,omitempty"`
+	// True if the PermissionClaim is Bound.
+	Bound bool `json:"bound"`
+	// Human readable reason, if the PermissionClaim is not bound.
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	// Bound is True when the claim is bound on all selected TargetClusters.
+	ClusterSetPermissionClaimBound = "Bound"
+)
+
+// ClusterSetPermissionClaim claims the same permissions on a set of TargetClusters,
+// by creating a PermissionClaim for each of them.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Selected",type="integer",JSONPath=".status.selectedClusters"
+// +kubebuilder:printcolumn:name="Bound",type="integer",JSONPath=".status.boundClusters"
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type ClusterSetPermissionClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterSetPermissionClaimSpec `json:"spec,omitempty"`
+	// +kubebuilder:default={phase:Pending}
+	Status ClusterSetPermissionClaimStatus `json:"status,omitempty"`
+}
+
+// ClusterSetPermissionClaimList contains a list of ClusterSetPermissionClaims
+// +kubebuilder:object:root=true
+type ClusterSetPermissionClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSetPermissionClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterSetPermissionClaim{}, &ClusterSetPermissionClaimList{})
+}
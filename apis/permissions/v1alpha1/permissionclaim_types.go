@@ -7,38 +7,266 @@ import (
 
 // PermissionClaimSpec defines the desired state of a PermissionClaim.
 type PermissionClaimSpec struct {
+	// TargetCluster to claim permissions on.
+	// Defaults to the target cluster the operator was started with.
+	// Immutable.
+	TargetClusterRef *TargetClusterReference `json:"targetClusterRef,omitempty"`
 	// Namespace to claim permissions in.
-	// This is the namespace that the ServiceAccount and namespaced-scoped Roles will live.
+	// This is the home namespace of the ServiceAccount and the first namespace namespaced-scoped Roles will live in.
 	Namespace string `json:"namespace"`
+	// Additional namespaces to claim namespace-scoped permissions in.
+	// A Role and RoleBinding for the ServiceAccount is created in each of them.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selects namespaces on the target cluster to claim namespace-scoped permissions in.
+	// Namespaces are added and removed dynamically as their labels change.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 	// Name of the secret to house the created credentials.
 	SecretName string `json:"secretName"`
 	// Namespace-scoped permissions.
 	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// Namespace-scoped permissions for individual namespaces.
+	// Rules are added to the permissions from .spec.rules,
+	// if the namespace is also one of the claims namespaces.
+	NamespacedRules []NamespacedRules `json:"namespacedRules,omitempty"`
 	// Cluster-scoped permissions.
 	ClusterRules []rbacv1.PolicyRule `json:"clusterRules,omitempty"`
+	// Names of existing ClusterRoles on the target cluster
+	// to bind within each of the claims namespaces.
+	RoleRefs []string `json:"roleRefs,omitempty"`
+	// Names of existing ClusterRoles on the target cluster to bind cluster-wide.
+	ClusterRoleRefs []string `json:"clusterRoleRefs,omitempty"`
+	// Configures how credentials for the ServiceAccount are issued.
+	Credentials PermissionClaimCredentials `json:"credentials,omitempty"`
+	// Revokes all granted permissions and credentials
+	// once this duration has passed since the claim was created.
+	ExpiresAfter *metav1.Duration `json:"expiresAfter,omitempty"`
+	// Revokes all granted permissions and credentials at this point in time.
+	// When both expiresAfter and expiresAt are set, the earlier deadline applies.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Deletes the PermissionClaim when it expires.
+	DeleteOnExpiry bool `json:"deleteOnExpiry,omitempty"`
+	// Suspends the claim by removing all RoleBindings and ClusterRoleBindings from the target cluster.
+	// The ServiceAccount and Roles are kept and bindings are restored when the claim is resumed.
+	Suspended bool `json:"suspended,omitempty"`
+	// Also revokes the issued credentials while the claim is suspended.
+	// Tokens issued via the TokenRequest API can not be revoked
+	// without deleting the ServiceAccount and stay valid until they expire.
+	RevokeCredentialsWhenSuspended bool `json:"revokeCredentialsWhenSuspended,omitempty"`
 }
 
+// TargetClusterReference references a TargetCluster.
+type TargetClusterReference struct {
+	// Name of the TargetCluster.
+	Name string `json:"name"`
+}
+
+// NamespacedRules grants permissions in a single namespace.
+type NamespacedRules struct {
+	// Namespace to grant permissions in.
+	Namespace string `json:"namespace"`
+	// Namespace-scoped permissions.
+	Rules []rbacv1.PolicyRule `json:"rules"`
+}
+
+// PermissionClaimCredentials configures the credentials issued for a PermissionClaim.
+type PermissionClaimCredentials struct {
+	// Type of credentials to issue.
+	// ServiceAccountTokenSecret (default) uses a legacy, non-expiring ServiceAccount token Secret.
+	// TokenRequest mints bound, time-limited tokens via the TokenRequest API.
+	// +kubebuilder:validation:Enum=ServiceAccountTokenSecret;TokenRequest
+	Type PermissionClaimCredentialsType `json:"type,omitempty"`
+	// Requested lifetime of tokens issued via the TokenRequest API.
+	// The target cluster may choose to issue tokens with a different lifetime.
+	// +kubebuilder:validation:Minimum=600
+	// +kubebuilder:default=3600
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+	// Interval after which tokens issued via the TokenRequest API are replaced.
+	// Defaults to 80% of the token lifetime.
+	// Intervals not shorter than the token lifetime are ignored in favor of the default.
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+}
+
+type PermissionClaimCredentialsType string
+
+const (
+	PermissionClaimCredentialsServiceAccountTokenSecret PermissionClaimCredentialsType = "ServiceAccountTokenSecret"
+	PermissionClaimCredentialsTokenRequest              PermissionClaimCredentialsType = "TokenRequest"
+)
+
 // PermissionClaimStatus defines the observed state of a PermissionClaim
 type PermissionClaimStatus struct {
+	// The most recent generation observed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The most recent generation that has been fully applied to the target cluster.
+	// Lags behind observedGeneration while spec changes are held back,
+	// e.g. pending approval or denied by policy.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
 	// Conditions is a list of status conditions ths object is in.
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// DEPRECATED: This field is not part of any API contract
 	// it will go away as soon as kubectl can print conditions!
 	// Human readable status - please use .Conditions from code
 	Phase PermissionClaimPhase `json:"phase,omitempty"`
+	// Credentials reports on the currently issued credentials.
+	Credentials *PermissionClaimCredentialsStatus `json:"credentials,omitempty"`
+	// Objects created on the target cluster for this claim.
+	ManagedObjects []ManagedObjectReference `json:"managedObjects,omitempty"`
+	// Status of namespace-scoped permissions per namespace.
+	Namespaces []PermissionClaimNamespaceStatus `json:"namespaces,omitempty"`
+	// Namespaces currently matched by .spec.namespaceSelector.
+	MatchedNamespaces []string `json:"matchedNamespaces,omitempty"`
+	// Hash of the current spec.
+	// PermissionClaimApprovals have to reference this hash to approve the claim.
+	SpecHash string `json:"specHash,omitempty"`
+	// Hash of the spec, requester and referenced ClusterRoles
+	// the permissions of the requester were last checked for.
+	// The check is only repeated when this hash changes.
+	RequesterCheckHash string `json:"requesterCheckHash,omitempty"`
+	// Last revocation of credentials requested via the revoke-credentials annotation.
+	LastRevocation *CredentialsRevocation `json:"lastRevocation,omitempty"`
+	// Time the claim expires, computed from .spec.expiresAfter and .spec.expiresAt.
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Privilege escalation paths found in the claimed permissions.
+	RiskAssessment *RiskAssessment `json:"riskAssessment,omitempty"`
+}
+
+// RiskAssessment reports on privilege escalation paths in the claimed permissions.
+type RiskAssessment struct {
+	// Highest severity of all findings.
+	Severity RiskSeverity `json:"severity"`
+	// Individual findings.
+	Findings []RiskFinding `json:"findings,omitempty"`
+}
+
+// RiskFinding describes a single privilege escalation path.
+type RiskFinding struct {
+	// Severity of the finding.
+	Severity RiskSeverity `json:"severity"`
+	// Type of escalation path.
+	Type string `json:"type"`
+	// Path of the offending field in the spec.
+	Field string `json:"field"`
+	// Human readable description.
+	Message string `json:"message"`
 }
 
+// +kubebuilder:validation:Enum=None;Low;Medium;High;Critical
+type RiskSeverity string
+
 const (
+	RiskSeverityNone     RiskSeverity = "None"
+	RiskSeverityLow      RiskSeverity = "Low"
+	RiskSeverityMedium   RiskSeverity = "Medium"
+	RiskSeverityHigh     RiskSeverity = "High"
+	RiskSeverityCritical RiskSeverity = "Critical"
+)
+
+// PermissionClaimNamespaceStatus reports on the permissions in a single namespace.
+type PermissionClaimNamespaceStatus struct {
+	// Name of the namespace.
+	Name string `json:"name"`
+	// True if the Role and RoleBinding in this namespace are established.
+	Ready bool `json:"ready"`
+	// Human readable reason, if the namespace is not ready.
+	Message string `json:"message,omitempty"`
+}
+
+// ManagedObjectReference references an object created on the target cluster.
+type ManagedObjectReference struct {
+	// API Group of the object.
+	Group string `json:"group,omitempty"`
+	// Kind of the object.
+	Kind string `json:"kind"`
+	// Name of the object.
+	Name string `json:"name"`
+	// Namespace of the object, empty for cluster-scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PermissionClaimCredentialsStatus reports on the currently issued credentials.
+type PermissionClaimCredentialsStatus struct {
+	// Time the current credentials were issued.
+	IssueTime *metav1.Time `json:"issueTime,omitempty"`
+	// Time the current credentials expire.
+	// Empty for credentials that never expire.
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+	// Time the credentials were last replaced by new ones.
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// SHA-256 hash of the token issued via the TokenRequest API.
+	// Tokens in the kubeconfig Secret not matching this hash are replaced.
+	TokenHash string `json:"tokenHash,omitempty"`
+}
+
+// CredentialsRevocation records a revocation of issued credentials.
+type CredentialsRevocation struct {
+	// Time credentials were revoked.
+	Time metav1.Time `json:"time"`
+	// Reason given in the revoke-credentials annotation.
+	Reason string `json:"reason,omitempty"`
+}
+
+const (
+	// Bound is True when all permissions and credentials of the claim have been established.
+	// While False, the reason names the first readiness condition that is not True.
 	PermissionClaimBound = "Bound"
+	// ServiceAccountReady is True when the ServiceAccount exists on the target cluster.
+	PermissionClaimServiceAccountReady = "ServiceAccountReady"
+	// RolesReady is True when the ClusterRole and all Roles exist on the target cluster.
+	PermissionClaimRolesReady = "RolesReady"
+	// BindingsReady is True when all RoleBindings and ClusterRoleBindings exist on the target cluster.
+	PermissionClaimBindingsReady = "BindingsReady"
+	// CredentialsReady is True when credentials have been issued
+	// and the kubeconfig Secret is up to date.
+	PermissionClaimCredentialsReady = "CredentialsReady"
+	// Approved is True when the current spec of the claim has been approved,
+	// if approval is required.
+	PermissionClaimApproved = "Approved"
+	// Denied is True when the claim requests permissions
+	// not allowed by the PermissionPolicies that apply to it.
+	PermissionClaimDenied = "Denied"
+	// KubeconfigOutOfDate is True while the kubeconfig Secret
+	// does not yet reflect the currently issued credentials.
+	PermissionClaimKubeconfigOutOfDate = "KubeconfigOutOfDate"
+	// EscalationRisk is True when the claimed permissions allow privilege escalation.
+	PermissionClaimEscalationRisk = "EscalationRisk"
+	// RequesterAuthorized is False when the user who last changed the claim
+	// does not hold the permissions requested by it.
+	PermissionClaimRequesterAuthorized = "RequesterAuthorized"
+	// RoleRefsResolved is False while ClusterRoles referenced
+	// via .spec.roleRefs or .spec.clusterRoleRefs are missing.
+	PermissionClaimRoleRefsResolved = "RoleRefsResolved"
+	// Expired is True after the deadline set via .spec.expiresAfter or .spec.expiresAt has passed.
+	PermissionClaimExpired = "Expired"
+	// Suspended is True while the claim is suspended via .spec.suspended.
+	PermissionClaimSuspended = "Suspended"
+	// Terminating is True while objects on the target cluster are cleaned up.
+	PermissionClaimTerminating = "Terminating"
+	// TargetClusterReachable is False while the API of the target cluster can not be reached.
+	PermissionClaimTargetClusterReachable = "TargetClusterReachable"
 )
 
+// RequesterAnnotation holds the JSON encoded authenticationv1.UserInfo
+// of the user who last changed the spec of a PermissionClaim or ClusterSetPermissionClaim.
+// Maintained by the mutating webhooks, PermissionClaims of a ClusterSetPermissionClaim
+// inherit the requester of the set.
+const RequesterAnnotation = "permissions.thetechnick.ninja/requester"
+
+// RevokeCredentialsAnnotation requests all credentials issued for a PermissionClaim to be revoked and replaced.
+// The value is recorded as reason of the revocation and the annotation is removed, once credentials are revoked.
+const RevokeCredentialsAnnotation = "permissions.thetechnick.ninja/revoke-credentials"
+
 type PermissionClaimPhase string
 
 // Well-known PermissionClaim Phases for printing a Status in kubectl,
 // see deprecation notice in PermissionClaimStatus for details.
 const (
-	PermissionClaimPhasePending PermissionClaimPhase = "Pending"
-	PermissionClaimPhaseBound   PermissionClaimPhase = "Bound"
+	PermissionClaimPhasePending         PermissionClaimPhase = "Pending"
+	PermissionClaimPhasePendingApproval PermissionClaimPhase = "PendingApproval"
+	PermissionClaimPhaseBound           PermissionClaimPhase = "Bound"
+	PermissionClaimPhaseDenied          PermissionClaimPhase = "Denied"
+	PermissionClaimPhaseExpired         PermissionClaimPhase = "Expired"
+	PermissionClaimPhaseSuspended       PermissionClaimPhase = "Suspended"
+	PermissionClaimPhaseTerminating     PermissionClaimPhase = "Terminating"
 )
 
 // PermissionClaim controls the handover process between two operators.
@@ -0,0 +1,44 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PermissionClaimApprovalSpec defines the desired state of a PermissionClaimApproval.
+type PermissionClaimApprovalSpec struct {
+	// Name of the PermissionClaim in the same namespace to approve.
+	ClaimName string `json:"claimName"`
+	// Hash of the approved PermissionClaim spec, as reported in .status.specHash of the PermissionClaim.
+	// Any change to the spec of the PermissionClaim invalidates the approval.
+	SpecHash string `json:"specHash"`
+}
+
+// ApproverAnnotation holds the JSON encoded authenticationv1.UserInfo
+// of the user who created a PermissionClaimApproval or last changed its spec.
+// Maintained by the mutating webhook, approvals by the requester of the PermissionClaim are ignored.
+const ApproverAnnotation = "permissions.thetechnick.ninja/approver"
+
+// PermissionClaimApproval approves the exact spec of a PermissionClaim.
+// Approvals are only honored when created by someone else than the requester of the claim.
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Claim",type="string",JSONPath=".spec.claimName"
+// +kubebuilder:printcolumn:name="Spec Hash",type="string",JSONPath=".spec.specHash"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type PermissionClaimApproval struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PermissionClaimApprovalSpec `json:"spec,omitempty"`
+}
+
+// PermissionClaimApprovalList contains a list of PermissionClaimApprovals
+// +kubebuilder:object:root=true
+type PermissionClaimApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PermissionClaimApproval `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PermissionClaimApproval{}, &PermissionClaimApprovalList{})
+}
@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PermissionPolicySpec defines the desired state of a PermissionPolicy.
+type PermissionPolicySpec struct {
+	// Namespaces of the management cluster this policy applies to.
+	// Applies to PermissionClaims in all namespaces if empty.
+	ClaimNamespaces []string `json:"claimNamespaces,omitempty"`
+	// Namespace-scoped permissions claims may request.
+	AllowedRules []rbacv1.PolicyRule `json:"allowedRules,omitempty"`
+	// Cluster-scoped permissions claims may request.
+	AllowedClusterRules []rbacv1.PolicyRule `json:"allowedClusterRules,omitempty"`
+	// Namespaces on the target cluster claims may request permissions in.
+	// "*" allows all namespaces.
+	AllowedTargetNamespaces []string `json:"allowedTargetNamespaces,omitempty"`
+	// ClusterRoles claims may reference via .spec.roleRefs and .spec.clusterRoleRefs.
+	// "*" allows all ClusterRoles.
+	AllowedClusterRoleRefs []string `json:"allowedClusterRoleRefs,omitempty"`
+	// TargetClusters claims may reference via .spec.targetClusterRef.
+	// "*" allows all TargetClusters.
+	// Claims for the default target cluster of the operator are always allowed.
+	AllowedTargetClusters []string `json:"allowedTargetClusters,omitempty"`
+}
+
+// PermissionPolicy caps the permissions PermissionClaims may request.
+// PermissionClaims matched by at least one policy may only request permissions allowed by one of them.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type PermissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PermissionPolicySpec `json:"spec,omitempty"`
+}
+
+// PermissionPolicyList contains a list of PermissionPolicies
+// +kubebuilder:object:root=true
+type PermissionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PermissionPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PermissionPolicy{}, &PermissionPolicyList{})
+}
@@ -0,0 +1,86 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TargetClusterSpec defines the desired state of a TargetCluster.
+type TargetClusterSpec struct {
+	// Secret containing the kubeconfig the operator uses to manage permissions on the target cluster.
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+	// Secret containing the kubeconfig template to render kubeconfigs for PermissionClaims from.
+	// Authentication in the template is replaced with the credentials issued for the claim.
+	// Defaults to the kubeconfig referenced in .spec.kubeconfigSecretRef.
+	TemplateKubeconfigSecretRef *SecretKeyReference `json:"templateKubeconfigSecretRef,omitempty"`
+}
+
+// SecretKeyReference references a key in a Secret on the management cluster.
+type SecretKeyReference struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+	// Namespace of the Secret.
+	// Must be the namespace the operator is running in,
+	// as the operator is only allowed to read Secrets there.
+	Namespace string `json:"namespace"`
+	// Key in the Secret.
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+}
+
+// TargetClusterStatus defines the observed state of a TargetCluster.
+type TargetClusterStatus struct {
+	// The most recent generation observed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions is a list of status conditions ths object is in.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// DEPRECATED: This field is not part of any API contract
+	// it will go away as soon as kubectl can print conditions!
+	// Human readable status - please use .Conditions from code
+	Phase TargetClusterPhase `json:"phase,omitempty"`
+}
+
+const (
+	// Available is True when clients for the target cluster have been set up,
+	// its API is reachable and PermissionClaims referencing it are reconciled.
+	TargetClusterAvailable = "Available"
+	// Terminating is True while PermissionClaims still reference a deleted TargetCluster.
+	TargetClusterTerminating = "Terminating"
+)
+
+type TargetClusterPhase string
+
+// Well-known TargetCluster Phases for printing a Status in kubectl,
+// see deprecation notice in TargetClusterStatus for details.
+const (
+	TargetClusterPhasePending     TargetClusterPhase = "Pending"
+	TargetClusterPhaseAvailable   TargetClusterPhase = "Available"
+	TargetClusterPhaseUnreachable TargetClusterPhase = "Unreachable"
+	TargetClusterPhaseTerminating TargetClusterPhase = "Terminating"
+)
+
+// TargetCluster registers a cluster PermissionClaims can claim permissions on.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type TargetCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TargetClusterSpec `json:"spec,omitempty"`
+	// +kubebuilder:default={phase:Pending}
+	Status TargetClusterStatus `json:"status,omitempty"`
+}
+
+// TargetClusterList contains a list of TargetClusters
+// +kubebuilder:object:root=true
+type TargetClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TargetCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TargetCluster{}, &TargetClusterList{})
+}
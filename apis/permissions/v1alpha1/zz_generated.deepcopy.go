@@ -5,11 +5,211 @@
 package v1alpha1
 
 import (
-	"k8s.io/api/rbac/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetKubeconfig) DeepCopyInto(out *ClusterSetKubeconfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetKubeconfig.
+func (in *ClusterSetKubeconfig) DeepCopy() *ClusterSetKubeconfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetKubeconfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetPermissionClaim) DeepCopyInto(out *ClusterSetPermissionClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetPermissionClaim.
+func (in *ClusterSetPermissionClaim) DeepCopy() *ClusterSetPermissionClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetPermissionClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterSetPermissionClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetPermissionClaimList) DeepCopyInto(out *ClusterSetPermissionClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterSetPermissionClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetPermissionClaimList.
+func (in *ClusterSetPermissionClaimList) DeepCopy() *ClusterSetPermissionClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetPermissionClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterSetPermissionClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetPermissionClaimSpec) DeepCopyInto(out *ClusterSetPermissionClaimSpec) {
+	*out = *in
+	in.TargetClusterSelector.DeepCopyInto(&out.TargetClusterSelector)
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Kubeconfig != nil {
+		in, out := &in.Kubeconfig, &out.Kubeconfig
+		*out = new(ClusterSetKubeconfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetPermissionClaimSpec.
+func (in *ClusterSetPermissionClaimSpec) DeepCopy() *ClusterSetPermissionClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetPermissionClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetPermissionClaimStatus) DeepCopyInto(out *ClusterSetPermissionClaimStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.KubeconfigContexts != nil {
+		in, out := &in.KubeconfigContexts, &out.KubeconfigContexts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetClusters != nil {
+		in, out := &in.TargetClusters, &out.TargetClusters
+		*out = make([]ClusterSetTargetClusterStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetPermissionClaimStatus.
+func (in *ClusterSetPermissionClaimStatus) DeepCopy() *ClusterSetPermissionClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetPermissionClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSetTargetClusterStatus) DeepCopyInto(out *ClusterSetTargetClusterStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSetTargetClusterStatus.
+func (in *ClusterSetTargetClusterStatus) DeepCopy() *ClusterSetTargetClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSetTargetClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsRevocation) DeepCopyInto(out *CredentialsRevocation) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRevocation.
+func (in *CredentialsRevocation) DeepCopy() *CredentialsRevocation {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsRevocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedObjectReference) DeepCopyInto(out *ManagedObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedObjectReference.
+func (in *ManagedObjectReference) DeepCopy() *ManagedObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedRules) DeepCopyInto(out *NamespacedRules) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedRules.
+func (in *NamespacedRules) DeepCopy() *NamespacedRules {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PermissionClaim) DeepCopyInto(out *PermissionClaim) {
 	*out = *in
@@ -37,6 +237,131 @@ func (in *PermissionClaim) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimApproval) DeepCopyInto(out *PermissionClaimApproval) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimApproval.
+func (in *PermissionClaimApproval) DeepCopy() *PermissionClaimApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PermissionClaimApproval) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimApprovalList) DeepCopyInto(out *PermissionClaimApprovalList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PermissionClaimApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimApprovalList.
+func (in *PermissionClaimApprovalList) DeepCopy() *PermissionClaimApprovalList {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimApprovalList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PermissionClaimApprovalList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimApprovalSpec) DeepCopyInto(out *PermissionClaimApprovalSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimApprovalSpec.
+func (in *PermissionClaimApprovalSpec) DeepCopy() *PermissionClaimApprovalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimApprovalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimCredentials) DeepCopyInto(out *PermissionClaimCredentials) {
+	*out = *in
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RotationInterval != nil {
+		in, out := &in.RotationInterval, &out.RotationInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimCredentials.
+func (in *PermissionClaimCredentials) DeepCopy() *PermissionClaimCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimCredentialsStatus) DeepCopyInto(out *PermissionClaimCredentialsStatus) {
+	*out = *in
+	if in.IssueTime != nil {
+		in, out := &in.IssueTime, &out.IssueTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimCredentialsStatus.
+func (in *PermissionClaimCredentialsStatus) DeepCopy() *PermissionClaimCredentialsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimCredentialsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PermissionClaimList) DeepCopyInto(out *PermissionClaimList) {
 	*out = *in
@@ -69,23 +394,80 @@ func (in *PermissionClaimList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionClaimNamespaceStatus) DeepCopyInto(out *PermissionClaimNamespaceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimNamespaceStatus.
+func (in *PermissionClaimNamespaceStatus) DeepCopy() *PermissionClaimNamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionClaimNamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PermissionClaimSpec) DeepCopyInto(out *PermissionClaimSpec) {
 	*out = *in
+	if in.TargetClusterRef != nil {
+		in, out := &in.TargetClusterRef, &out.TargetClusterRef
+		*out = new(TargetClusterReference)
+		**out = **in
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Rules != nil {
 		in, out := &in.Rules, &out.Rules
-		*out = make([]v1.PolicyRule, len(*in))
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NamespacedRules != nil {
+		in, out := &in.NamespacedRules, &out.NamespacedRules
+		*out = make([]NamespacedRules, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 	if in.ClusterRules != nil {
 		in, out := &in.ClusterRules, &out.ClusterRules
-		*out = make([]v1.PolicyRule, len(*in))
+		*out = make([]rbacv1.PolicyRule, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RoleRefs != nil {
+		in, out := &in.RoleRefs, &out.RoleRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterRoleRefs != nil {
+		in, out := &in.ClusterRoleRefs, &out.ClusterRoleRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Credentials.DeepCopyInto(&out.Credentials)
+	if in.ExpiresAfter != nil {
+		in, out := &in.ExpiresAfter, &out.ExpiresAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimSpec.
@@ -103,11 +485,45 @@ func (in *PermissionClaimStatus) DeepCopyInto(out *PermissionClaimStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(PermissionClaimCredentialsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagedObjects != nil {
+		in, out := &in.ManagedObjects, &out.ManagedObjects
+		*out = make([]ManagedObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]PermissionClaimNamespaceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchedNamespaces != nil {
+		in, out := &in.MatchedNamespaces, &out.MatchedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastRevocation != nil {
+		in, out := &in.LastRevocation, &out.LastRevocation
+		*out = new(CredentialsRevocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RiskAssessment != nil {
+		in, out := &in.RiskAssessment, &out.RiskAssessment
+		*out = new(RiskAssessment)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionClaimStatus.
@@ -119,3 +535,277 @@ func (in *PermissionClaimStatus) DeepCopy() *PermissionClaimStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionPolicy) DeepCopyInto(out *PermissionPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionPolicy.
+func (in *PermissionPolicy) DeepCopy() *PermissionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PermissionPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionPolicyList) DeepCopyInto(out *PermissionPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PermissionPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionPolicyList.
+func (in *PermissionPolicyList) DeepCopy() *PermissionPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PermissionPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionPolicySpec) DeepCopyInto(out *PermissionPolicySpec) {
+	*out = *in
+	if in.ClaimNamespaces != nil {
+		in, out := &in.ClaimNamespaces, &out.ClaimNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRules != nil {
+		in, out := &in.AllowedRules, &out.AllowedRules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AllowedClusterRules != nil {
+		in, out := &in.AllowedClusterRules, &out.AllowedClusterRules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AllowedTargetNamespaces != nil {
+		in, out := &in.AllowedTargetNamespaces, &out.AllowedTargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedClusterRoleRefs != nil {
+		in, out := &in.AllowedClusterRoleRefs, &out.AllowedClusterRoleRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedTargetClusters != nil {
+		in, out := &in.AllowedTargetClusters, &out.AllowedTargetClusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionPolicySpec.
+func (in *PermissionPolicySpec) DeepCopy() *PermissionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RiskAssessment) DeepCopyInto(out *RiskAssessment) {
+	*out = *in
+	if in.Findings != nil {
+		in, out := &in.Findings, &out.Findings
+		*out = make([]RiskFinding, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RiskAssessment.
+func (in *RiskAssessment) DeepCopy() *RiskAssessment {
+	if in == nil {
+		return nil
+	}
+	out := new(RiskAssessment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RiskFinding) DeepCopyInto(out *RiskFinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RiskFinding.
+func (in *RiskFinding) DeepCopy() *RiskFinding {
+	if in == nil {
+		return nil
+	}
+	out := new(RiskFinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetCluster) DeepCopyInto(out *TargetCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetCluster.
+func (in *TargetCluster) DeepCopy() *TargetCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TargetCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetClusterList) DeepCopyInto(out *TargetClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TargetCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetClusterList.
+func (in *TargetClusterList) DeepCopy() *TargetClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TargetClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetClusterReference) DeepCopyInto(out *TargetClusterReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetClusterReference.
+func (in *TargetClusterReference) DeepCopy() *TargetClusterReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetClusterReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetClusterSpec) DeepCopyInto(out *TargetClusterSpec) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+	if in.TemplateKubeconfigSecretRef != nil {
+		in, out := &in.TemplateKubeconfigSecretRef, &out.TemplateKubeconfigSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetClusterSpec.
+func (in *TargetClusterSpec) DeepCopy() *TargetClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetClusterStatus) DeepCopyInto(out *TargetClusterStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetClusterStatus.
+func (in *TargetClusterStatus) DeepCopy() *TargetClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -7,20 +7,25 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	permissionapis "github.com/thetechnick/permission-claim-operator/apis"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
 	"github.com/thetechnick/permission-claim-operator/internal/controllers"
+	"github.com/thetechnick/permission-claim-operator/internal/escalation"
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+	"github.com/thetechnick/permission-claim-operator/internal/targetclusters"
+	"github.com/thetechnick/permission-claim-operator/internal/webhooks"
 )
 
 var (
@@ -43,6 +48,13 @@ type opts struct {
 	probeAddr               string
 	targetClusterKubeconfig string
 	templateKubeconfig      string
+	requireApproval         bool
+	checkRequester          bool
+	enableWebhooks          bool
+	disallowWildcards       bool
+	blockEscalationRisk     string
+	privilegedNamespaces    string
+	operatorUsername        string
 }
 
 func main() {
@@ -55,8 +67,33 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&opts.probeAddr, "health-probe-bind-address", ":8081",
 		"The address the probe endpoint binds to.")
-	flag.StringVar(&opts.targetClusterKubeconfig, "target-cluster-kubeconfig-file", "", "Target cluster kubeconfig.")
-	flag.StringVar(&opts.templateKubeconfig, "template-kubeconfig-file", "", "Template kubeconfig to create new ones from.")
+	flag.StringVar(&opts.targetClusterKubeconfig, "target-cluster-kubeconfig-file", "",
+		"Kubeconfig of the default target cluster, used by PermissionClaims not referencing a TargetCluster. "+
+			"Empty disables the default target cluster.")
+	flag.StringVar(&opts.templateKubeconfig, "template-kubeconfig-file", "",
+		"Template kubeconfig to create new ones for the default target cluster from. "+
+			"Defaults to the target cluster kubeconfig.")
+	flag.BoolVar(&opts.requireApproval, "require-approval", false,
+		"Require PermissionClaims to be approved via PermissionClaimApprovals before permissions are granted. "+
+			"With the mutating webhooks, approvals by the requester of a PermissionClaim are ignored, "+
+			"otherwise restrict who may create PermissionClaimApprovals via RBAC.")
+	flag.BoolVar(&opts.checkRequester, "check-requester-permissions", false,
+		"Deny PermissionClaims requesting permissions the user who last changed them does not hold on the target cluster. "+
+			"Requires the mutating webhook.")
+	flag.BoolVar(&opts.enableWebhooks, "enable-webhooks", false,
+		"Serve admission webhooks on port 9443.")
+	flag.BoolVar(&opts.disallowWildcards, "disallow-wildcards", false,
+		"Reject PermissionClaims with wildcard rules in the validating webhook.")
+	flag.StringVar(&opts.blockEscalationRisk, "block-escalation-risk", "",
+		"Stop reconciling PermissionClaims with a privilege escalation risk at or above this severity "+
+			"(Low, Medium, High or Critical). Empty disables blocking.")
+	flag.StringVar(&opts.privilegedNamespaces, "privileged-namespaces",
+		strings.Join(escalation.DefaultPrivilegedNamespaces, ","),
+		"Comma separated list of namespaces in which namespaced permissions are treated as a critical escalation risk.")
+	flag.StringVar(&opts.operatorUsername, "operator-username",
+		"system:serviceaccount:"+os.Getenv("PKO_NAMESPACE")+":permission-claim-operator",
+		"Username the operator authenticates as, allowed to preset the requester of PermissionClaims "+
+			"it creates for ClusterSetPermissionClaims.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -125,57 +162,111 @@ func run(opts opts) error {
 		}
 	}
 
-	// TargetCluster Kubeconfig
-	templateKubeconfig, err := clientcmd.LoadFromFile(opts.templateKubeconfig)
-	if err != nil {
-		return fmt.Errorf("reading target cluster kubeconfig: %w", err)
+	// Default TargetCluster
+	var defaultTargetCluster *targetclusters.Cluster
+	if len(opts.targetClusterKubeconfig) > 0 {
+		defaultTargetCluster, err = newDefaultTargetCluster(opts)
+		if err != nil {
+			return err
+		}
+		if err := mgr.Add(defaultTargetCluster.Cache); err != nil {
+			return fmt.Errorf("adding target cluster cache to manager: %w", err)
+		}
 	}
-
-	// TargetCluster clients
-	targetCfg, err := clientcmd.BuildConfigFromFlags("", opts.targetClusterKubeconfig)
-	if err != nil {
-		return fmt.Errorf("reading target cluster kubeconfig: %w", err)
+	targetClusters := targetclusters.NewPool(
+		ctrl.Log.WithName("targetclusters"), targetScheme, defaultTargetCluster)
+	if err := mgr.Add(targetClusters); err != nil {
+		return fmt.Errorf("adding target cluster pool to manager: %w", err)
 	}
-	targetMapper, err := apiutil.NewDiscoveryRESTMapper(targetCfg)
-	if err != nil {
-		return fmt.Errorf("creating target cluster rest mapper: %w", err)
+	if err := mgr.AddReadyzCheck("target-cluster", targetClusters.ReadyzCheck); err != nil {
+		return fmt.Errorf("unable to set up target cluster ready check: %w", err)
 	}
-	targetClient, err := client.New(targetCfg, client.Options{
-		Scheme: targetScheme,
-		Mapper: targetMapper,
-	})
-	if err != nil {
-		return fmt.Errorf("creating target cluster client: %w", err)
-	}
-	targetCache, err := cache.New(targetCfg, cache.Options{
-		Scheme: targetScheme,
-		Mapper: targetMapper,
-	})
-	if err != nil {
-		return fmt.Errorf("creating target cluster cache: %w", err)
-	}
-	if err := mgr.Add(targetCache); err != nil {
-		return fmt.Errorf("adding target cluster cache to manager: %w", err)
+
+	var blockRiskSeverity permissionsv1alpha1.RiskSeverity
+	if len(opts.blockEscalationRisk) > 0 {
+		blockRiskSeverity, err = escalation.ParseSeverity(opts.blockEscalationRisk)
+		if err != nil {
+			return fmt.Errorf("parsing -block-escalation-risk: %w", err)
+		}
 	}
-	targetCachedClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
-		CacheReader: targetCache,
-		Client:      targetClient,
-	})
-	if err != nil {
-		return fmt.Errorf("creating cached client for target cluster: %w", err)
+	var privilegedNamespaces []string
+	if len(opts.privilegedNamespaces) > 0 {
+		privilegedNamespaces = strings.Split(opts.privilegedNamespaces, ",")
 	}
+	riskAnalyzer := escalation.NewAnalyzer(privilegedNamespaces)
 
 	// Package
 	if err = (controllers.NewPermissionClaimController(
 		ctrl.Log.WithName("controllers").WithName("ClusterPackage"),
-		mgr.GetClient(), mgr.GetScheme(), templateKubeconfig, targetCachedClient, targetCache,
+		mgr.GetClient(), mgr.GetScheme(), mgr.GetEventRecorderFor("permission-claim-operator"),
+		targetClusters, opts.requireApproval, opts.checkRequester, riskAnalyzer, blockRiskSeverity,
 	).SetupWithManager(mgr)); err != nil {
 		return fmt.Errorf("unable to create controller for ClusterPackage: %w", err)
 	}
 
+	// ClusterSetPermissionClaim
+	if err = (controllers.NewClusterSetPermissionClaimController(
+		ctrl.Log.WithName("controllers").WithName("ClusterSetPermissionClaim"),
+		mgr.GetClient(), mgr.GetScheme(),
+	).SetupWithManager(mgr)); err != nil {
+		return fmt.Errorf("unable to create controller for ClusterSetPermissionClaim: %w", err)
+	}
+
+	// TargetCluster
+	if err = (controllers.NewTargetClusterController(
+		ctrl.Log.WithName("controllers").WithName("TargetCluster"),
+		mgr.GetClient(), targetClusters, opts.namespace,
+	).SetupWithManager(mgr)); err != nil {
+		return fmt.Errorf("unable to create controller for TargetCluster: %w", err)
+	}
+
+	// Metrics
+	if err := ctrlmetrics.Registry.Register(metrics.NewClaimsCollector(mgr.GetClient())); err != nil {
+		return fmt.Errorf("registering PermissionClaim metrics: %w", err)
+	}
+
+	// Webhooks
+	if opts.enableWebhooks {
+		claimValidator := webhooks.NewPermissionClaimValidator(opts.disallowWildcards, riskAnalyzer)
+		mgr.GetWebhookServer().Register(webhooks.PermissionClaimValidatingPath, &webhook.Admission{
+			Handler: claimValidator,
+		})
+		mgr.GetWebhookServer().Register(webhooks.PermissionClaimMutatingPath, &webhook.Admission{
+			Handler: webhooks.NewPermissionClaimRequesterAnnotator(opts.operatorUsername),
+		})
+		mgr.GetWebhookServer().Register(webhooks.ClusterSetPermissionClaimValidatingPath, &webhook.Admission{
+			Handler: webhooks.NewClusterSetPermissionClaimValidator(claimValidator),
+		})
+		mgr.GetWebhookServer().Register(webhooks.ClusterSetPermissionClaimMutatingPath, &webhook.Admission{
+			Handler: webhooks.NewClusterSetPermissionClaimRequesterAnnotator(),
+		})
+		mgr.GetWebhookServer().Register(webhooks.PermissionClaimApprovalMutatingPath, &webhook.Admission{
+			Handler: webhooks.NewPermissionClaimApprovalApproverAnnotator(),
+		})
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		return fmt.Errorf("problem running manager: %w", err)
 	}
 	return nil
 }
+
+// sets up the target cluster configured via flags,
+// used by PermissionClaims not referencing a TargetCluster.
+func newDefaultTargetCluster(opts opts) (*targetclusters.Cluster, error) {
+	templateKubeconfigFile := opts.templateKubeconfig
+	if len(templateKubeconfigFile) == 0 {
+		templateKubeconfigFile = opts.targetClusterKubeconfig
+	}
+	templateKubeconfig, err := clientcmd.LoadFromFile(templateKubeconfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading template kubeconfig: %w", err)
+	}
+
+	targetCfg, err := clientcmd.BuildConfigFromFlags("", opts.targetClusterKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("reading target cluster kubeconfig: %w", err)
+	}
+	return targetclusters.NewCluster("", targetCfg, targetScheme, templateKubeconfig)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// specHash returns a hash over the complete spec of the claim.
+func specHash(claim *permissionsv1alpha1.PermissionClaim) (string, error) {
+	j, err := json.Marshal(claim.Spec)
+	if err != nil {
+		return "", fmt.Errorf("marshalling spec: %w", err)
+	}
+	hash := sha256.Sum256(j)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// checkApproval reports whether the current spec of the claim is approved
+// and updates the Approved condition accordingly.
+func (c *PermissionClaimController) checkApproval(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (bool, error) {
+	hash, err := specHash(claim)
+	if err != nil {
+		return false, err
+	}
+	claim.Status.SpecHash = hash
+
+	if !c.requireApproval {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimApproved)
+		return true, nil
+	}
+
+	approvalList := &permissionsv1alpha1.PermissionClaimApprovalList{}
+	if err := c.client.List(ctx, approvalList, client.InNamespace(claim.Namespace)); err != nil {
+		return false, fmt.Errorf("listing PermissionClaimApprovals: %w", err)
+	}
+	requester := annotatedUsername(claim, permissionsv1alpha1.RequesterAnnotation)
+	var selfApprovals []string
+	for _, approval := range approvalList.Items {
+		if approval.Spec.ClaimName != claim.Name ||
+			approval.Spec.SpecHash != hash {
+			continue
+		}
+		// The approver has to be someone else than the requester.
+		// Without the mutating webhooks neither is known
+		// and approvers have to be separated via RBAC on PermissionClaimApprovals.
+		if len(requester) > 0 &&
+			annotatedUsername(&approval, permissionsv1alpha1.ApproverAnnotation) == requester {
+			selfApprovals = append(selfApprovals, approval.Name)
+			continue
+		}
+
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimApproved,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Approved",
+			Message:            fmt.Sprintf("Approved by PermissionClaimApproval %s.", approval.Name),
+			ObservedGeneration: claim.Generation,
+		})
+		return true, nil
+	}
+
+	message := fmt.Sprintf("Waiting for a PermissionClaimApproval with specHash %s.", hash)
+	if len(selfApprovals) > 0 {
+		message += fmt.Sprintf(" Ignoring PermissionClaimApprovals by the requester %s: %s.",
+			requester, strings.Join(selfApprovals, ", "))
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimApproved,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PendingApproval",
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePendingApproval
+	return false, nil
+}
+
+// returns the username recorded in the given user annotation of obj,
+// or an empty string if the annotation is missing or invalid.
+func annotatedUsername(obj client.Object, annotation string) string {
+	user := &authenticationv1.UserInfo{}
+	if err := json.Unmarshal([]byte(obj.GetAnnotations()[annotation]), user); err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+// enqueueApprovedClaim maps PermissionClaimApproval events to the approved PermissionClaim.
+func enqueueApprovedClaim(obj client.Object) []reconcile.Request {
+	approval, ok := obj.(*permissionsv1alpha1.PermissionClaimApproval)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: client.ObjectKey{
+			Name:      approval.Spec.ClaimName,
+			Namespace: approval.Namespace,
+		},
+	}}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckApproval(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := permissionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		requester string
+		approver  string
+		want      bool
+	}{
+		{
+			name:      "approved by someone else",
+			requester: `{"username":"alice"}`,
+			approver:  `{"username":"bob"}`,
+			want:      true,
+		},
+		{
+			name:      "approved by the requester",
+			requester: `{"username":"alice"}`,
+			approver:  `{"username":"alice"}`,
+		},
+		{
+			// webhooks not installed.
+			name: "unknown requester",
+			want: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claim := &permissionsv1alpha1.PermissionClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       permissionsv1alpha1.PermissionClaimSpec{Namespace: "ns"},
+			}
+			if len(test.requester) > 0 {
+				claim.Annotations = map[string]string{
+					permissionsv1alpha1.RequesterAnnotation: test.requester,
+				}
+			}
+			hash, err := specHash(claim)
+			if err != nil {
+				t.Fatal(err)
+			}
+			approval := &permissionsv1alpha1.PermissionClaimApproval{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: permissionsv1alpha1.PermissionClaimApprovalSpec{
+					ClaimName: "test",
+					SpecHash:  hash,
+				},
+			}
+			if len(test.approver) > 0 {
+				approval.Annotations = map[string]string{
+					permissionsv1alpha1.ApproverAnnotation: test.approver,
+				}
+			}
+
+			c := &PermissionClaimController{
+				log:             logr.Discard(),
+				client:          fake.NewClientBuilder().WithScheme(scheme).WithObjects(approval).Build(),
+				scheme:          scheme,
+				requireApproval: true,
+			}
+			approved, err := c.checkApproval(context.Background(), claim)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if approved != test.want {
+				t.Errorf("approved = %v, want %v", approved, test.want)
+			}
+		})
+	}
+}
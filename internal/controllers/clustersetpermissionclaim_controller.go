@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// ClusterSetPermissionClaimController fans ClusterSetPermissionClaims out
+// into a PermissionClaim per selected TargetCluster.
+type ClusterSetPermissionClaimController struct {
+	log    logr.Logger
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+func NewClusterSetPermissionClaimController(
+	log logr.Logger,
+	client client.Client,
+	scheme *runtime.Scheme,
+) *ClusterSetPermissionClaimController {
+	return &ClusterSetPermissionClaimController{
+		log:    log,
+		client: client,
+		scheme: scheme,
+	}
+}
+
+func (c *ClusterSetPermissionClaimController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	set := &permissionsv1alpha1.ClusterSetPermissionClaim{}
+	if err := c.client.Get(ctx, req.NamespacedName, set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !set.GetDeletionTimestamp().IsZero() {
+		// PermissionClaims are garbage collected via their owner reference.
+		return ctrl.Result{}, nil
+	}
+
+	original := set.DeepCopy()
+	res, err := c.reconcile(ctx, set)
+	set.Status.ObservedGeneration = set.Generation
+	if perr := c.client.Status().Patch(ctx, set, client.MergeFrom(original)); perr != nil &&
+		!errors.IsNotFound(perr) {
+		if err != nil {
+			c.log.Error(perr, "patching status", "ClusterSetPermissionClaim", req.NamespacedName.String())
+			return res, err
+		}
+		return res, fmt.Errorf("patching status: %w", perr)
+	}
+	return res, err
+}
+
+func (c *ClusterSetPermissionClaimController) reconcile(
+	ctx context.Context, set *permissionsv1alpha1.ClusterSetPermissionClaim,
+) (ctrl.Result, error) {
+	// Expiry is tracked for the set as a whole,
+	// otherwise expired PermissionClaims would just be recreated.
+	now := time.Now()
+	expiresAt, expires := clusterSetExpirationTime(set)
+	expired := expires && !now.Before(expiresAt)
+	set.Status.ExpirationTime = nil
+	if expires {
+		set.Status.ExpirationTime = &metav1.Time{Time: expiresAt}
+	}
+	if expired && set.Spec.Template.DeleteOnExpiry {
+		// PermissionClaims are garbage collected via their owner reference.
+		return ctrl.Result{}, client.IgnoreNotFound(c.client.Delete(ctx, set))
+	}
+	var res ctrl.Result
+	if expires && !expired {
+		res.RequeueAfter = expiresAt.Sub(now)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&set.Spec.TargetClusterSelector)
+	if err != nil {
+		// Retrying will not help, until the spec is fixed.
+		setClusterSetBoundCondition(set, metav1.ConditionFalse, "InvalidSelector", err.Error())
+		set.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+		return res, nil
+	}
+
+	targetClusterList := &permissionsv1alpha1.TargetClusterList{}
+	if err := c.client.List(ctx, targetClusterList,
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return res, fmt.Errorf("listing TargetClusters: %w", err)
+	}
+	var clusters []string
+	for _, targetCluster := range targetClusterList.Items {
+		// Deleted TargetClusters are only released, once no claim references them.
+		if !targetCluster.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		clusters = append(clusters, targetCluster.Name)
+	}
+	sort.Strings(clusters)
+
+	claimList := &permissionsv1alpha1.PermissionClaimList{}
+	if err := c.client.List(ctx, claimList, client.InNamespace(set.Namespace)); err != nil {
+		return res, fmt.Errorf("listing PermissionClaims: %w", err)
+	}
+	existingClaims := map[string]*permissionsv1alpha1.PermissionClaim{}
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		if metav1.IsControlledBy(claim, set) {
+			existingClaims[targetClusterName(claim)] = claim
+		}
+	}
+
+	var (
+		statuses []permissionsv1alpha1.ClusterSetTargetClusterStatus
+		errs     []error
+	)
+	for _, cluster := range clusters {
+		existingClaim := existingClaims[cluster]
+		delete(existingClaims, cluster)
+		if expired && existingClaim == nil {
+			// Recreating the PermissionClaim would grant access again.
+			continue
+		}
+		claim, err := c.reconcileClaim(ctx, set, cluster, existingClaim)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TargetCluster %s: %w", cluster, err))
+			statuses = append(statuses, permissionsv1alpha1.ClusterSetTargetClusterStatus{
+				Name:       cluster,
+				ClaimName:  clusterSetClaimName(set, cluster),
+				SecretName: clusterSetSecretName(set, cluster),
+				Message:    err.Error(),
+			})
+			continue
+		}
+		statuses = append(statuses, clusterSetTargetClusterStatus(cluster, claim))
+	}
+
+	// Claims for TargetClusters no longer selected.
+	for _, claim := range existingClaims {
+		if !claim.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err := c.client.Delete(ctx, claim); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("deleting PermissionClaim %s: %w", claim.Name, err))
+		}
+	}
+
+	updateClusterSetStatus(set, statuses)
+	if expired {
+		setClusterSetBoundCondition(set, metav1.ConditionFalse, "Expired",
+			fmt.Sprintf("Expired at %s, permissions and credentials have been revoked.",
+				expiresAt.UTC().Format(time.RFC3339)))
+		set.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseExpired
+	}
+
+	if err := c.reconcileKubeconfigSecret(ctx, set, statuses); err != nil {
+		errs = append(errs, fmt.Errorf("reconciling kubeconfig Secret: %w", err))
+	}
+	return res, utilerrors.NewAggregate(errs)
+}
+
+// Returns the point in time the ClusterSetPermissionClaim expires at,
+// expiresAfter counts from the creation of the set.
+// Returns false if it never expires.
+func clusterSetExpirationTime(set *permissionsv1alpha1.ClusterSetPermissionClaim) (time.Time, bool) {
+	expiresAt, expires := specExpirationTime(set.CreationTimestamp, &set.Spec.Template)
+	if !expires {
+		return time.Time{}, false
+	}
+	// Truncated to the precision of metav1.Time,
+	// so the deadline copied to PermissionClaims does not change after a round trip.
+	return metav1.NewTime(expiresAt).Rfc3339Copy().Time, true
+}
+
+// reconcileClaim ensures the PermissionClaim for the given TargetCluster matches the template.
+func (c *ClusterSetPermissionClaimController) reconcileClaim(
+	ctx context.Context, set *permissionsv1alpha1.ClusterSetPermissionClaim,
+	cluster string, existingClaim *permissionsv1alpha1.PermissionClaim,
+) (*permissionsv1alpha1.PermissionClaim, error) {
+	desiredClaim := &permissionsv1alpha1.PermissionClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterSetClaimName(set, cluster),
+			Namespace: set.Namespace,
+		},
+		Spec: *set.Spec.Template.DeepCopy(),
+	}
+	desiredClaim.Spec.TargetClusterRef = &permissionsv1alpha1.TargetClusterReference{Name: cluster}
+	desiredClaim.Spec.SecretName = clusterSetSecretName(set, cluster)
+	// PermissionClaims expire with the set, not relative to their own creation.
+	desiredClaim.Spec.ExpiresAfter = nil
+	desiredClaim.Spec.ExpiresAt = nil
+	if expiresAt, expires := clusterSetExpirationTime(set); expires {
+		desiredClaim.Spec.ExpiresAt = &metav1.Time{Time: expiresAt}
+	}
+	// Requester checks have to apply to the user who changed the set,
+	// not the operator creating the PermissionClaims.
+	requester := set.Annotations[permissionsv1alpha1.RequesterAnnotation]
+	if len(requester) > 0 {
+		desiredClaim.Annotations = map[string]string{
+			permissionsv1alpha1.RequesterAnnotation: requester,
+		}
+	}
+	if err := controllerutil.SetControllerReference(set, desiredClaim, c.scheme); err != nil {
+		return nil, fmt.Errorf("set controller-reference: %w", err)
+	}
+
+	if existingClaim == nil {
+		if err := c.client.Create(ctx, desiredClaim); errors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("PermissionClaim %s already exists and is not controlled by this ClusterSetPermissionClaim",
+				desiredClaim.Name)
+		} else if err != nil {
+			return nil, fmt.Errorf("creating PermissionClaim: %w", err)
+		}
+		return desiredClaim, nil
+	}
+
+	if !equality.Semantic.DeepEqual(desiredClaim.Spec, existingClaim.Spec) ||
+		existingClaim.Annotations[permissionsv1alpha1.RequesterAnnotation] != requester {
+		existingClaim.Spec = desiredClaim.Spec
+		if len(requester) > 0 {
+			if existingClaim.Annotations == nil {
+				existingClaim.Annotations = map[string]string{}
+			}
+			existingClaim.Annotations[permissionsv1alpha1.RequesterAnnotation] = requester
+		} else {
+			delete(existingClaim.Annotations, permissionsv1alpha1.RequesterAnnotation)
+		}
+		if err := c.client.Update(ctx, existingClaim); err != nil {
+			return nil, fmt.Errorf("updating PermissionClaim: %w", err)
+		}
+	}
+	return existingClaim, nil
+}
+
+// returns the name of the PermissionClaim created for the given TargetCluster.
+func clusterSetClaimName(set *permissionsv1alpha1.ClusterSetPermissionClaim, cluster string) string {
+	return truncateName(set.Name+"-"+cluster, validation.DNS1123SubdomainMaxLength)
+}
+
+// returns the name of the kubeconfig Secret for the given TargetCluster.
+func clusterSetSecretName(set *permissionsv1alpha1.ClusterSetPermissionClaim, cluster string) string {
+	return truncateName(set.Spec.Template.SecretName+"-"+cluster, validation.DNS1123SubdomainMaxLength)
+}
+
+func clusterSetTargetClusterStatus(
+	cluster string, claim *permissionsv1alpha1.PermissionClaim,
+) permissionsv1alpha1.ClusterSetTargetClusterStatus {
+	status := permissionsv1alpha1.ClusterSetTargetClusterStatus{
+		Name:       cluster,
+		ClaimName:  claim.Name,
+		SecretName: claim.Spec.SecretName,
+		Phase:      claim.Status.Phase,
+	}
+	bound := meta.FindStatusCondition(claim.Status.Conditions, permissionsv1alpha1.PermissionClaimBound)
+	switch {
+	case bound == nil:
+		status.Message = "Not yet reconciled."
+	case bound.Status == metav1.ConditionTrue:
+		status.Bound = true
+	default:
+		status.Message = bound.Message
+	}
+	return status
+}
+
+// updateClusterSetStatus aggregates the status of all TargetClusters into the Bound condition.
+func updateClusterSetStatus(
+	set *permissionsv1alpha1.ClusterSetPermissionClaim,
+	statuses []permissionsv1alpha1.ClusterSetTargetClusterStatus,
+) {
+	var notBound []string
+	for _, status := range statuses {
+		if !status.Bound {
+			notBound = append(notBound, status.Name)
+		}
+	}
+	set.Status.TargetClusters = statuses
+	set.Status.SelectedClusters = int32(len(statuses))
+	set.Status.BoundClusters = int32(len(statuses) - len(notBound))
+
+	switch {
+	case len(statuses) == 0:
+		setClusterSetBoundCondition(set, metav1.ConditionFalse,
+			"NoTargetClusters", "No TargetClusters are selected.")
+		set.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+	case len(notBound) > 0:
+		setClusterSetBoundCondition(set, metav1.ConditionFalse, "NotBoundOnAllClusters",
+			fmt.Sprintf("Bound on %d of %d TargetClusters, not bound on: %s.",
+				set.Status.BoundClusters, set.Status.SelectedClusters, strings.Join(notBound, ", ")))
+		set.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+	default:
+		setClusterSetBoundCondition(set, metav1.ConditionTrue, "PermissionsEstablished",
+			fmt.Sprintf("Bound on all %d selected TargetClusters.", set.Status.SelectedClusters))
+		set.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseBound
+	}
+}
+
+func setClusterSetBoundCondition(
+	set *permissionsv1alpha1.ClusterSetPermissionClaim,
+	status metav1.ConditionStatus, reason, message string,
+) {
+	meta.SetStatusCondition(&set.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.ClusterSetPermissionClaimBound,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: set.Generation,
+	})
+}
+
+func (c *ClusterSetPermissionClaimController) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&permissionsv1alpha1.ClusterSetPermissionClaim{}).
+		Owns(&permissionsv1alpha1.PermissionClaim{}).
+		Owns(&corev1.Secret{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(c.enqueueClusterSetForClaimSecret),
+		).
+		Watches(
+			&source.Kind{Type: &permissionsv1alpha1.TargetCluster{}},
+			handler.EnqueueRequestsFromMapFunc(c.enqueueClusterSetsForTargetCluster),
+		).
+		Complete(c)
+}
+
+// enqueueClusterSetsForTargetCluster maps TargetCluster events
+// to all ClusterSetPermissionClaims selecting the TargetCluster now or before the event.
+// Since the previous labels are unknown, all ClusterSetPermissionClaims are enqueued.
+func (c *ClusterSetPermissionClaimController) enqueueClusterSetsForTargetCluster(obj client.Object) []reconcile.Request {
+	setList := &permissionsv1alpha1.ClusterSetPermissionClaimList{}
+	if err := c.client.List(context.Background(), setList); err != nil {
+		c.log.Error(err, "listing ClusterSetPermissionClaims for TargetCluster event", "TargetCluster", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, set := range setList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&set),
+		})
+	}
+	return requests
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conditions that all have to be True for a claim to be Bound, in reconcile order.
+var readinessConditions = []string{
+	permissionsv1alpha1.PermissionClaimRolesReady,
+	permissionsv1alpha1.PermissionClaimServiceAccountReady,
+	permissionsv1alpha1.PermissionClaimBindingsReady,
+	permissionsv1alpha1.PermissionClaimCredentialsReady,
+}
+
+// setReadinessCondition sets the given readiness condition
+// according to the outcome of the matching reconcile step.
+func setReadinessCondition(
+	claim *permissionsv1alpha1.PermissionClaim, conditionType string, err error,
+) {
+	if err != nil {
+		reason := "ReconcileFailed"
+		if isNotOwned(err) {
+			// Needs manual intervention, retrying will not help.
+			reason = "NotOwned"
+		}
+		setReadinessConditionFalse(claim, conditionType, reason, err.Error())
+		return
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Reconciled",
+		ObservedGeneration: claim.Generation,
+	})
+}
+
+func setReadinessConditionFalse(
+	claim *permissionsv1alpha1.PermissionClaim, conditionType, reason, message string,
+) {
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+}
+
+// stepFailed reports the error of a failed reconcile step in the given readiness condition
+// and Bound condition and returns it.
+func stepFailed(
+	claim *permissionsv1alpha1.PermissionClaim, conditionType string, err error,
+) error {
+	setReadinessCondition(claim, conditionType, err)
+	updateBoundCondition(claim)
+	return err
+}
+
+// updateBoundCondition derives the Bound condition from the readiness conditions.
+func updateBoundCondition(claim *permissionsv1alpha1.PermissionClaim) {
+	for _, conditionType := range readinessConditions {
+		cond := meta.FindStatusCondition(claim.Status.Conditions, conditionType)
+		if cond != nil && cond.Status == metav1.ConditionTrue {
+			continue
+		}
+
+		message := "Not yet reconciled."
+		if cond != nil {
+			message = cond.Message
+		}
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimBound,
+			Status:             metav1.ConditionFalse,
+			Reason:             strings.TrimSuffix(conditionType, "Ready") + "NotReady",
+			Message:            message,
+			ObservedGeneration: claim.Generation,
+		})
+		claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+		return
+	}
+
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimBound,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PermissionsEstablished",
+		Message:            "Permissions and credentials have been established.",
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseBound
+}
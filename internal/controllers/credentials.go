@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileCredentials ensures credentials for the given ServiceAccount exist
+// and returns the token to embed into the kubeconfig Secret.
+// An empty token is returned while credentials are not yet available.
+func (c *PermissionClaimController) reconcileCredentials(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	sa *corev1.ServiceAccount,
+) ([]byte, error) {
+	if claim.Spec.Credentials.Type == permissionsv1alpha1.PermissionClaimCredentialsTokenRequest {
+		return c.reconcileTokenRequest(ctx, claim, sa)
+	}
+
+	tokenSecret, err := c.reconcileTokenSecret(ctx, claim, sa)
+	if err != nil {
+		return nil, fmt.Errorf("reconciling token Secret: %w", err)
+	}
+	token := tokenSecret.Data[corev1.ServiceAccountTokenKey]
+	if len(token) > 0 {
+		if claim.Status.Credentials == nil || claim.Status.Credentials.IssueTime == nil ||
+			!claim.Status.Credentials.IssueTime.Equal(&tokenSecret.CreationTimestamp) {
+			c.recorder.Event(claim, corev1.EventTypeNormal, eventReasonCredentialsIssued,
+				"Issued ServiceAccount token via token Secret")
+		}
+		// legacy ServiceAccount tokens never expire.
+		claim.Status.Credentials = &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+			IssueTime: tokenSecret.CreationTimestamp.DeepCopy(),
+		}
+	}
+	return token, nil
+}
+
+// reconcileTokenRequest returns the currently issued token,
+// or mints a new one via the TokenRequest API when no valid token exists
+// or the current token is due for rotation.
+func (c *PermissionClaimController) reconcileTokenRequest(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	sa *corev1.ServiceAccount,
+) ([]byte, error) {
+	// Make sure no long-lived token is left behind
+	// when switching from ServiceAccountTokenSecret to TokenRequest.
+	if err := c.deleteTokenSecret(ctx, claim); err != nil {
+		return nil, err
+	}
+	return c.requestToken(ctx, claim, sa, claim.Spec.Credentials.ExpirationSeconds)
+}
+
+// requestToken returns the current token from the kubeconfig Secret,
+// or mints a new one with the given lifetime when it is missing or due for rotation.
+func (c *PermissionClaimController) requestToken(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	sa *corev1.ServiceAccount, expirationSeconds *int64,
+) ([]byte, error) {
+	now := time.Now()
+	rotateAt, ok := tokenRotationTime(claim)
+	if ok && now.Before(rotateAt) {
+		token, err := c.currentKubeconfigToken(ctx, claim)
+		if err != nil {
+			return nil, err
+		}
+		// The kubeconfig Secret is writable by the consumer,
+		// so only reuse tokens that were issued by us.
+		if len(token) > 0 && tokenHash(token) == claim.Status.Credentials.TokenHash {
+			return token, nil
+		}
+		if len(token) > 0 && len(claim.Status.Credentials.TokenHash) > 0 {
+			// the token was replaced by someone else.
+			c.recordDriftRepaired(claim, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:      claim.Spec.SecretName,
+				Namespace: claim.Namespace,
+			}})
+		}
+	}
+
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: expirationSeconds,
+		},
+	}
+	tokenRequest, err := c.targetServiceAccounts.
+		ServiceAccounts(sa.Namespace).
+		CreateToken(ctx, sa.Name, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("requesting token: %w", err)
+	}
+
+	newCreds := &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+		IssueTime:      &metav1.Time{Time: now},
+		ExpirationTime: tokenRequest.Status.ExpirationTimestamp.DeepCopy(),
+		TokenHash:      tokenHash([]byte(tokenRequest.Status.Token)),
+	}
+	if ok {
+		// a previous token is replaced.
+		c.log.Info("rotated token",
+			"PermissionClaim", client.ObjectKeyFromObject(claim).String(),
+			"expirationTime", newCreds.ExpirationTime)
+		newCreds.LastRotationTime = &metav1.Time{Time: now}
+		c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonCredentialsRotated,
+			"Rotated token, new token expires at %s", expirationString(newCreds.ExpirationTime))
+	} else {
+		if claim.Status.Credentials != nil {
+			newCreds.LastRotationTime = claim.Status.Credentials.LastRotationTime
+		}
+		c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonCredentialsIssued,
+			"Issued token via TokenRequest API, expires at %s", expirationString(newCreds.ExpirationTime))
+	}
+	claim.Status.Credentials = newCreds
+	return []byte(tokenRequest.Status.Token), nil
+}
+
+// keepCredentials keeps rotating TokenRequest credentials issued for the last
+// reconciled state of the claim, while changes to its spec wait for approval.
+// Returns res with its requeue shortened to the next rotation.
+func (c *PermissionClaimController) keepCredentials(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim, res ctrl.Result,
+) (ctrl.Result, error) {
+	creds := claim.Status.Credentials
+	if creds == nil || creds.IssueTime == nil || creds.ExpirationTime == nil {
+		// nothing issued yet, or credentials that do not expire.
+		return res, nil
+	}
+
+	sa, err := c.managedServiceAccount(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if sa == nil {
+		return res, nil
+	}
+
+	// Keep the lifetime of the current token,
+	// the requested lifetime may not be approved yet.
+	expirationSeconds := int64(creds.ExpirationTime.Sub(creds.IssueTime.Time).Seconds())
+	token, err := c.requestToken(ctx, claim, sa, &expirationSeconds)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("rotating credentials: %w", err)
+	}
+	if err := c.reconcileKubeconfigSecret(ctx, claim, token); err != nil {
+		return ctrl.Result{}, fmt.Errorf("reconcile Kubeconfig Secret: %w", err)
+	}
+	return ctrl.Result{RequeueAfter: minRequeueAfter(
+		credentialsRequeueAfter(claim, time.Now()),
+		res.RequeueAfter,
+	)}, nil
+}
+
+// returns the ServiceAccount recorded as managed by the claim,
+// or nil if it does not exist (anymore).
+func (c *PermissionClaimController) managedServiceAccount(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (*corev1.ServiceAccount, error) {
+	for _, ref := range claim.Status.ManagedObjects {
+		if ref.Group != "" || ref.Kind != "ServiceAccount" {
+			continue
+		}
+		sa := &corev1.ServiceAccount{}
+		err := c.targetClient.Get(ctx, client.ObjectKey{
+			Name:      ref.Name,
+			Namespace: ref.Namespace,
+		}, sa)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting ServiceAccount: %w", err)
+		}
+		if !c.ownerStrategy.IsOwner(claim, sa) {
+			return nil, nil
+		}
+		return sa, nil
+	}
+	return nil, nil
+}
+
+// deletes the legacy ServiceAccount token Secret, if it is owned by the claim.
+func (c *PermissionClaimController) deleteTokenSecret(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claim.Name + "-token",
+			Namespace: claim.Spec.Namespace,
+		},
+	}
+	err := c.targetClient.Get(ctx, client.ObjectKeyFromObject(tokenSecret), tokenSecret)
+	if errors.IsNotFound(err) {
+		forgetManagedObject(claim, managedObjectRef(tokenSecret))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting token Secret: %w", err)
+	}
+	if !c.ownerStrategy.IsOwner(claim, tokenSecret) {
+		return nil
+	}
+
+	if err := c.targetClient.Delete(ctx, tokenSecret); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting token Secret: %w", err)
+	}
+	forgetManagedObject(claim, managedObjectRef(tokenSecret))
+	return nil
+}
+
+// returns the token embedded in the existing kubeconfig Secret.
+func (c *PermissionClaimController) currentKubeconfigToken(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) ([]byte, error) {
+	secret := &corev1.Secret{}
+	err := c.client.Get(ctx, client.ObjectKey{
+		Name:      claim.Spec.SecretName,
+		Namespace: claim.Namespace,
+	}, secret)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting kubeconfig Secret: %w", err)
+	}
+
+	return kubeconfigToken(secret.Data[corev1.ServiceAccountKubeconfigKey]), nil
+}
+
+// returns the first token found in the given kubeconfig.
+func kubeconfigToken(data []byte) []byte {
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		// a broken kubeconfig is replaced with new credentials.
+		return nil
+	}
+	for _, authInfo := range kubeconfig.AuthInfos {
+		if len(authInfo.Token) > 0 {
+			return []byte(authInfo.Token)
+		}
+	}
+	return nil
+}
+
+// returns the hex encoded SHA-256 hash of the given token.
+func tokenHash(token []byte) string {
+	hash := sha256.Sum256(token)
+	return hex.EncodeToString(hash[:])
+}
+
+// Fraction of the token lifetime after which tokens are rotated,
+// when no explicit rotation interval is configured.
+const defaultRotationFraction = 0.8
+
+// tokenRotationTime returns the point in time the current token has to be replaced.
+// Returns false if the claim has no expiring credentials.
+func tokenRotationTime(claim *permissionsv1alpha1.PermissionClaim) (time.Time, bool) {
+	creds := claim.Status.Credentials
+	if creds == nil || creds.ExpirationTime == nil {
+		return time.Time{}, false
+	}
+	if creds.IssueTime == nil {
+		return creds.ExpirationTime.Time, true
+	}
+
+	lifetime := creds.ExpirationTime.Sub(creds.IssueTime.Time)
+	interval := time.Duration(float64(lifetime) * defaultRotationFraction)
+	if ri := claim.Spec.Credentials.RotationInterval; ri != nil &&
+		ri.Duration > 0 && ri.Duration < lifetime {
+		interval = ri.Duration
+	}
+	return creds.IssueTime.Add(interval), true
+}
+
+// credentialsRequeueAfter returns the duration after which
+// the claim has to be reconciled again to rotate expiring credentials.
+func credentialsRequeueAfter(claim *permissionsv1alpha1.PermissionClaim, now time.Time) time.Duration {
+	rotateAt, ok := tokenRotationTime(claim)
+	if !ok {
+		return 0
+	}
+	if d := rotateAt.Sub(now); d > 0 {
+		return d
+	}
+	return time.Second
+}
+
+// revokeCredentials invalidates all credentials issued for the claim,
+// if requested via the revoke-credentials annotation.
+// New credentials are issued by the next reconcile.
+// Returns true if credentials were revoked.
+func (c *PermissionClaimController) revokeCredentials(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (bool, error) {
+	reason, ok := claim.Annotations[permissionsv1alpha1.RevokeCredentialsAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	// Legacy tokens are invalidated with their Secret,
+	// TokenRequest tokens only with the ServiceAccount they are bound to.
+	kind := "Secret"
+	if claim.Spec.Credentials.Type == permissionsv1alpha1.PermissionClaimCredentialsTokenRequest {
+		kind = "ServiceAccount"
+	}
+	if _, err := c.deleteManagedObjects(ctx, claim, kind); err != nil {
+		return false, fmt.Errorf("deleting %s: %w", kind, err)
+	}
+	// Also ensures a TokenRequest token is not reused from the kubeconfig.
+	if err := c.deleteKubeconfigSecret(ctx, claim); err != nil {
+		return false, err
+	}
+
+	c.log.Info("revoked credentials",
+		"PermissionClaim", client.ObjectKeyFromObject(claim).String(), "reason", reason)
+	c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonCredentialsRevoked,
+		"Revoked credentials: %s", reason)
+	claim.Status.LastRevocation = &permissionsv1alpha1.CredentialsRevocation{
+		Time:   metav1.Now(),
+		Reason: reason,
+	}
+	claim.Status.Credentials = nil
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimKubeconfigOutOfDate,
+		Status:             metav1.ConditionTrue,
+		Reason:             "CredentialsRevoked",
+		Message:            "Credentials have been revoked, new credentials are being issued.",
+		ObservedGeneration: claim.Generation,
+	})
+	setReadinessConditionFalse(claim, permissionsv1alpha1.PermissionClaimCredentialsReady,
+		"CredentialsRevoked", "Credentials have been revoked, new credentials are being issued.")
+	updateBoundCondition(claim)
+
+	// Patch a copy, to not overwrite the pending status changes of the claim.
+	updated := claim.DeepCopy()
+	delete(updated.Annotations, permissionsv1alpha1.RevokeCredentialsAnnotation)
+	if err := c.client.Patch(ctx, updated, client.MergeFrom(claim)); err != nil {
+		return false, fmt.Errorf("removing %s annotation: %w",
+			permissionsv1alpha1.RevokeCredentialsAnnotation, err)
+	}
+	return true, nil
+}
+
+func expirationString(t *metav1.Time) string {
+	if t == nil {
+		return "unknown time"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/ownerhandling"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTokenRotationTime(t *testing.T) {
+	issued := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	expires := issued.Add(10 * time.Hour)
+
+	tests := []struct {
+		name             string
+		credentials      *permissionsv1alpha1.PermissionClaimCredentialsStatus
+		rotationInterval *metav1.Duration
+		want             time.Time
+		wantOK           bool
+	}{
+		{
+			name: "no credentials",
+		},
+		{
+			name: "credentials without expiry",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				IssueTime: &metav1.Time{Time: issued},
+			},
+		},
+		{
+			name: "unknown issue time",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				ExpirationTime: &metav1.Time{Time: expires},
+			},
+			want:   expires,
+			wantOK: true,
+		},
+		{
+			name: "default fraction of the lifetime",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				IssueTime:      &metav1.Time{Time: issued},
+				ExpirationTime: &metav1.Time{Time: expires},
+			},
+			want:   issued.Add(8 * time.Hour),
+			wantOK: true,
+		},
+		{
+			name: "rotation interval",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				IssueTime:      &metav1.Time{Time: issued},
+				ExpirationTime: &metav1.Time{Time: expires},
+			},
+			rotationInterval: &metav1.Duration{Duration: time.Hour},
+			want:             issued.Add(time.Hour),
+			wantOK:           true,
+		},
+		{
+			name: "rotation interval exceeding the lifetime",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				IssueTime:      &metav1.Time{Time: issued},
+				ExpirationTime: &metav1.Time{Time: expires},
+			},
+			rotationInterval: &metav1.Duration{Duration: 24 * time.Hour},
+			want:             issued.Add(8 * time.Hour),
+			wantOK:           true,
+		},
+		{
+			name: "rotation interval equal to the lifetime",
+			credentials: &permissionsv1alpha1.PermissionClaimCredentialsStatus{
+				IssueTime:      &metav1.Time{Time: issued},
+				ExpirationTime: &metav1.Time{Time: expires},
+			},
+			rotationInterval: &metav1.Duration{Duration: 10 * time.Hour},
+			want:             issued.Add(8 * time.Hour),
+			wantOK:           true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claim := &permissionsv1alpha1.PermissionClaim{}
+			claim.Spec.Credentials.RotationInterval = test.rotationInterval
+			claim.Status.Credentials = test.credentials
+
+			got, ok := tokenRotationTime(claim)
+			if ok != test.wantOK {
+				t.Fatalf("tokenRotationTime() ok = %v, want %v", ok, test.wantOK)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("tokenRotationTime() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReconcileTokenSecret_Ownership(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := permissionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	claim := &permissionsv1alpha1.PermissionClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: types.UID("1234")},
+		Spec:       permissionsv1alpha1.PermissionClaimSpec{Namespace: "ns"},
+	}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"}}
+
+	tests := []struct {
+		name            string
+		owned           bool
+		serviceAccount  string
+		wantNotOwnedErr bool
+	}{
+		{
+			name:           "owned",
+			owned:          true,
+			serviceAccount: "test",
+		},
+		{
+			name:            "not owned",
+			serviceAccount:  "test",
+			wantNotOwnedErr: true,
+		},
+		{
+			name:            "token of another ServiceAccount",
+			owned:           true,
+			serviceAccount:  "admin",
+			wantNotOwnedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existingSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-token",
+					Namespace:   "ns",
+					Annotations: map[string]string{corev1.ServiceAccountNameKey: test.serviceAccount},
+				},
+				Type: corev1.SecretTypeServiceAccountToken,
+			}
+			if test.owned {
+				if err := ownerhandling.Annotation.SetControllerReference(claim, existingSecret, scheme); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := &PermissionClaimController{
+				log:           logr.Discard(),
+				scheme:        scheme,
+				recorder:      record.NewFakeRecorder(10),
+				ownerStrategy: ownerhandling.Annotation,
+				targetClient:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingSecret).Build(),
+			}
+			_, err := c.reconcileTokenSecret(context.Background(), claim.DeepCopy(), sa)
+			if test.wantNotOwnedErr {
+				if !isNotOwned(err) {
+					t.Fatalf("error = %v, want NotOwnedError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NotOwnedError is returned when an object already exists on the target cluster,
+// but is not owned by the PermissionClaim trying to reconcile it.
+type NotOwnedError struct {
+	Kind string
+	Key  client.ObjectKey
+}
+
+func newNotOwnedError(kind string, obj client.Object) *NotOwnedError {
+	return &NotOwnedError{Kind: kind, Key: client.ObjectKeyFromObject(obj)}
+}
+
+func (e *NotOwnedError) Error() string {
+	return fmt.Sprintf("%s %s already exists and is not owned by this PermissionClaim", e.Kind, e.Key)
+}
+
+// isNotOwned returns true if err, or any error aggregated in it, is a NotOwnedError.
+func isNotOwned(err error) bool {
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		for _, e := range agg.Errors() {
+			if isNotOwned(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var notOwned *NotOwnedError
+	return errors.As(err, &notOwned)
+}
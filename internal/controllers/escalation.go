@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/escalation"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// assessEscalationRisk records privilege escalation paths of the claim in status
+// and updates the EscalationRisk condition accordingly.
+// Returns false if the risk is at or above the configured blocking threshold.
+func (c *PermissionClaimController) assessEscalationRisk(
+	claim *permissionsv1alpha1.PermissionClaim,
+) bool {
+	assessment := c.riskAnalyzer.Analyze(claim)
+	claim.Status.RiskAssessment = assessment
+
+	if len(assessment.Findings) == 0 {
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimEscalationRisk,
+			Status:             metav1.ConditionFalse,
+			Reason:             "NoRiskFound",
+			Message:            "No privilege escalation paths found.",
+			ObservedGeneration: claim.Generation,
+		})
+		return true
+	}
+
+	types := []string{}
+	for _, f := range assessment.Findings {
+		if !slices.Contains(types, f.Type) {
+			types = append(types, f.Type)
+		}
+	}
+	blocked := c.blockRiskSeverity != "" &&
+		escalation.AtLeast(assessment.Severity, c.blockRiskSeverity)
+
+	reason := string(assessment.Severity) + "Risk"
+	message := fmt.Sprintf("%s risk of privilege escalation: %s.",
+		assessment.Severity, strings.Join(types, ", "))
+	if blocked {
+		reason = "Blocked"
+		message += fmt.Sprintf(" Claims at or above %s risk are not reconciled.", c.blockRiskSeverity)
+		claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseDenied
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimEscalationRisk,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	return !blocked
+}
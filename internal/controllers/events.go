@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of Events recorded on PermissionClaims.
+const (
+	eventReasonCreated            = "Created"
+	eventReasonUpdated            = "Updated"
+	eventReasonDriftRepaired      = "DriftRepaired"
+	eventReasonPruned             = "Pruned"
+	eventReasonCredentialsIssued  = "CredentialsIssued"
+	eventReasonCredentialsRotated = "CredentialsRotated"
+	eventReasonCredentialsRevoked = "CredentialsRevoked"
+	eventReasonExpired            = "Expired"
+	eventReasonSuspended          = "Suspended"
+	eventReasonReconcileFailed    = "ReconcileFailed"
+	eventReasonCleanupCompleted   = "CleanupCompleted"
+
+	eventReasonTargetClusterUnreachable = "TargetClusterUnreachable"
+)
+
+// records the creation of an object on the target cluster.
+func (c *PermissionClaimController) recordCreated(
+	claim *permissionsv1alpha1.PermissionClaim, obj client.Object,
+) {
+	c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonCreated,
+		"Created %s %s on the target cluster", managedObjectRef(obj).Kind, objectName(obj))
+}
+
+// records an update of an object on the target cluster.
+// Changes while the spec of the claim is unchanged since it was last applied are drift,
+// e.g. caused by someone editing the object directly.
+// Spec changes held back until approval are not, even if observed before.
+func (c *PermissionClaimController) recordUpdated(
+	claim *permissionsv1alpha1.PermissionClaim, obj client.Object,
+) {
+	if claim.Status.AppliedGeneration == claim.Generation {
+		c.recordDriftRepaired(claim, obj)
+		return
+	}
+	c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonUpdated,
+		"Updated %s %s on the target cluster", managedObjectRef(obj).Kind, objectName(obj))
+}
+
+// records that changes someone else made to an object were reverted.
+func (c *PermissionClaimController) recordDriftRepaired(
+	claim *permissionsv1alpha1.PermissionClaim, obj client.Object,
+) {
+	metrics.DriftCorrections.WithLabelValues(managedObjectRef(obj).Kind).Inc()
+	c.recorder.Eventf(claim, corev1.EventTypeWarning, eventReasonDriftRepaired,
+		"Repaired drift of %s %s", managedObjectRef(obj).Kind, objectName(obj))
+}
+
+// returns "namespace/name" for namespaced and "name" for cluster-scoped objects.
+func objectName(obj client.Object) string {
+	if len(obj.GetNamespace()) == 0 {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+	"github.com/thetechnick/permission-claim-operator/internal/ownerhandling"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestRecordUpdated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := permissionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		appliedGeneration int64
+		wantReason        string
+		wantDrift         float64
+	}{
+		{
+			// e.g. a spec change approved after it was observed.
+			name:              "held back spec change",
+			appliedGeneration: 1,
+			wantReason:        eventReasonUpdated,
+		},
+		{
+			name:              "drift",
+			appliedGeneration: 2,
+			wantReason:        eventReasonDriftRepaired,
+			wantDrift:         1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claim := &permissionsv1alpha1.PermissionClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					UID:        types.UID("1234"),
+					Generation: 2,
+				},
+			}
+			claim.Status.ObservedGeneration = 2
+			claim.Status.AppliedGeneration = test.appliedGeneration
+
+			existingRole := &rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"},
+				}},
+			}
+			if err := ownerhandling.Annotation.SetControllerReference(claim, existingRole, scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			c := &PermissionClaimController{
+				log:           logr.Discard(),
+				scheme:        scheme,
+				recorder:      recorder,
+				ownerStrategy: ownerhandling.Annotation,
+				targetClient:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingRole).Build(),
+			}
+
+			drift := metrics.DriftCorrections.WithLabelValues("Role")
+			before := testutil.ToFloat64(drift)
+			_, err := c.reconcileRole(context.Background(), claim, "ns", []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"},
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := testutil.ToFloat64(drift) - before; got != test.wantDrift {
+				t.Errorf("drift corrections increased by %v, want %v", got, test.wantDrift)
+			}
+			select {
+			case event := <-recorder.Events:
+				// FakeRecorder events are formatted as "<type> <reason> <message>".
+				if fields := strings.Fields(event); len(fields) < 2 || fields[1] != test.wantReason {
+					t.Errorf("event = %q, want reason %s", event, test.wantReason)
+				}
+			default:
+				t.Errorf("no event recorded, want reason %s", test.wantReason)
+			}
+		})
+	}
+}
+
+func TestReconcileKubeconfigSecret_Drift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := permissionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := func(server, token string) []byte {
+		data, err := clientcmd.Write(*testKubeconfig(server, token, "ns"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name            string
+		kubeconfig      []byte
+		notControlled   bool
+		wantDrift       float64
+		wantNotOwnedErr bool
+	}{
+		{
+			name:       "rotated token",
+			kubeconfig: rendered("https://target", "old-token"),
+		},
+		{
+			name:       "changed server",
+			kubeconfig: rendered("https://attacker", "new-token"),
+			wantDrift:  1,
+		},
+		{
+			name:       "broken kubeconfig",
+			kubeconfig: []byte("broken"),
+			wantDrift:  1,
+		},
+		{
+			name:            "not controlled by the claim",
+			kubeconfig:      rendered("https://target", "old-token"),
+			notControlled:   true,
+			wantNotOwnedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claim := &permissionsv1alpha1.PermissionClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					UID:        types.UID("1234"),
+					Generation: 1,
+				},
+				Spec: permissionsv1alpha1.PermissionClaimSpec{SecretName: "kubeconfig"},
+			}
+			claim.Status.AppliedGeneration = 1
+
+			existingSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig", Namespace: "default"},
+				Data:       map[string][]byte{corev1.ServiceAccountKubeconfigKey: test.kubeconfig},
+			}
+			if !test.notControlled {
+				if err := controllerutil.SetControllerReference(claim, existingSecret, scheme); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := &PermissionClaimController{
+				log:            logr.Discard(),
+				client:         fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingSecret).Build(),
+				scheme:         scheme,
+				recorder:       record.NewFakeRecorder(10),
+				baseKubeconfig: testKubeconfig("https://target", "", "ns"),
+			}
+
+			drift := metrics.DriftCorrections.WithLabelValues("Secret")
+			before := testutil.ToFloat64(drift)
+			err := c.reconcileKubeconfigSecret(context.Background(), claim, []byte("new-token"))
+			if test.wantNotOwnedErr {
+				if !isNotOwned(err) {
+					t.Fatalf("error = %v, want NotOwnedError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := testutil.ToFloat64(drift) - before; got != test.wantDrift {
+				t.Errorf("drift corrections increased by %v, want %v", got, test.wantDrift)
+			}
+
+			secret := &corev1.Secret{}
+			if err := c.client.Get(context.Background(), client.ObjectKeyFromObject(existingSecret), secret); err != nil {
+				t.Fatal(err)
+			}
+			wantKubeconfig := rendered("https://target", "new-token")
+			if test.wantNotOwnedErr {
+				wantKubeconfig = test.kubeconfig
+			}
+			if got := string(secret.Data[corev1.ServiceAccountKubeconfigKey]); got != string(wantKubeconfig) {
+				t.Errorf("kubeconfig = %q, want %q", got, wantKubeconfig)
+			}
+		})
+	}
+}
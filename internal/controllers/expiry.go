@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Returns the point in time the claim expires at.
+// Returns false if the claim never expires.
+func expirationTime(claim *permissionsv1alpha1.PermissionClaim) (time.Time, bool) {
+	return specExpirationTime(claim.CreationTimestamp, &claim.Spec)
+}
+
+// Returns the point in time an object with the given spec and creation time expires at.
+// Returns false if it never expires.
+func specExpirationTime(
+	created metav1.Time, spec *permissionsv1alpha1.PermissionClaimSpec,
+) (time.Time, bool) {
+	var (
+		expiresAt time.Time
+		expires   bool
+	)
+	if spec.ExpiresAfter != nil {
+		expiresAt = created.Add(spec.ExpiresAfter.Duration)
+		expires = true
+	}
+	if spec.ExpiresAt != nil &&
+		(!expires || spec.ExpiresAt.Time.Before(expiresAt)) {
+		expiresAt = spec.ExpiresAt.Time
+		expires = true
+	}
+	return expiresAt, expires
+}
+
+// checkExpiry records the expiration time of the claim in status.
+// Returns true if the claim has expired.
+func checkExpiry(claim *permissionsv1alpha1.PermissionClaim, now time.Time) bool {
+	expiresAt, expires := expirationTime(claim)
+	if !expires {
+		claim.Status.ExpirationTime = nil
+		meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimExpired)
+		return false
+	}
+
+	claim.Status.ExpirationTime = &metav1.Time{Time: expiresAt}
+	if now.Before(expiresAt) {
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimExpired,
+			Status:             metav1.ConditionFalse,
+			Reason:             "NotExpired",
+			Message:            fmt.Sprintf("Expires at %s.", expiresAt.UTC().Format(time.RFC3339)),
+			ObservedGeneration: claim.Generation,
+		})
+		return false
+	}
+	return true
+}
+
+// expiryRequeueAfter returns the duration until the claim expires,
+// or 0 if the claim never expires.
+func expiryRequeueAfter(claim *permissionsv1alpha1.PermissionClaim, now time.Time) time.Duration {
+	expiresAt, expires := expirationTime(claim)
+	if !expires {
+		return 0
+	}
+	if d := expiresAt.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Returns the shortest of the given durations, ignoring 0.
+func minRequeueAfter(durations ...time.Duration) time.Duration {
+	var min time.Duration
+	for _, d := range durations {
+		if d > 0 && (min == 0 || d < min) {
+			min = d
+		}
+	}
+	return min
+}
+
+// handleExpiry revokes all permissions and credentials issued for an expired claim.
+// The claim itself is kept, unless .spec.deleteOnExpiry is set.
+func (c *PermissionClaimController) handleExpiry(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	if claim.Spec.DeleteOnExpiry {
+		// Cleanup on the target cluster is handled via the finalizer.
+		return client.IgnoreNotFound(c.client.Delete(ctx, claim))
+	}
+
+	// The token becomes invalid with the ServiceAccount.
+	remaining, err := c.deleteManagedObjects(ctx, claim)
+	if err != nil {
+		return err
+	}
+	claim.Status.ManagedObjects = remaining
+	claim.Status.Namespaces = nil
+
+	if err := c.deleteKubeconfigSecret(ctx, claim); err != nil {
+		return err
+	}
+
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimExpired,
+		Status:             metav1.ConditionTrue,
+		Reason:             "DeadlineExceeded",
+		Message:            fmt.Sprintf("Expired at %s.", claim.Status.ExpirationTime.UTC().Format(time.RFC3339)),
+		ObservedGeneration: claim.Generation,
+	})
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimBound,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Expired",
+		Message:            "Permissions and credentials have been revoked.",
+		ObservedGeneration: claim.Generation,
+	})
+	if claim.Status.Phase != permissionsv1alpha1.PermissionClaimPhaseExpired {
+		c.recorder.Event(claim, corev1.EventTypeNormal, eventReasonExpired,
+			"Claim expired, permissions and credentials have been revoked")
+	}
+	claim.Status.Credentials = nil
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseExpired
+	return nil
+}
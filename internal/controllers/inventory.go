@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// records the given object in the claims inventory of managed objects.
+func recordManagedObject(
+	claim *permissionsv1alpha1.PermissionClaim, ref permissionsv1alpha1.ManagedObjectReference,
+) {
+	claim.Status.ManagedObjects = appendManagedObjectRef(claim.Status.ManagedObjects, ref)
+}
+
+// appends the reference to the list, if it is not already part of it.
+func appendManagedObjectRef(
+	refs []permissionsv1alpha1.ManagedObjectReference, ref permissionsv1alpha1.ManagedObjectReference,
+) []permissionsv1alpha1.ManagedObjectReference {
+	if containsManagedObjectRef(refs, ref) {
+		return refs
+	}
+	return append(refs, ref)
+}
+
+// removes the given object from the claims inventory of managed objects.
+func forgetManagedObject(
+	claim *permissionsv1alpha1.PermissionClaim, ref permissionsv1alpha1.ManagedObjectReference,
+) {
+	var refs []permissionsv1alpha1.ManagedObjectReference
+	for _, existing := range claim.Status.ManagedObjects {
+		if existing != ref {
+			refs = append(refs, existing)
+		}
+	}
+	claim.Status.ManagedObjects = refs
+}
+
+func managedObjectRef(obj client.Object) permissionsv1alpha1.ManagedObjectReference {
+	ref := permissionsv1alpha1.ManagedObjectReference{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+	switch obj.(type) {
+	case *corev1.ServiceAccount:
+		ref.Kind = "ServiceAccount"
+	case *corev1.Secret:
+		ref.Kind = "Secret"
+	case *rbacv1.Role:
+		ref.Group, ref.Kind = rbacv1.GroupName, "Role"
+	case *rbacv1.ClusterRole:
+		ref.Group, ref.Kind = rbacv1.GroupName, "ClusterRole"
+	case *rbacv1.RoleBinding:
+		ref.Group, ref.Kind = rbacv1.GroupName, "RoleBinding"
+	case *rbacv1.ClusterRoleBinding:
+		ref.Group, ref.Kind = rbacv1.GroupName, "ClusterRoleBinding"
+	default:
+		panic(fmt.Sprintf("unsupported managed object type %T", obj))
+	}
+	return ref
+}
+
+// returns an empty object for the given reference.
+func objectForManagedObjectRef(ref permissionsv1alpha1.ManagedObjectReference) (client.Object, error) {
+	var obj client.Object
+	switch ref.Group + "/" + ref.Kind {
+	case "/ServiceAccount":
+		obj = &corev1.ServiceAccount{}
+	case "/Secret":
+		obj = &corev1.Secret{}
+	case rbacv1.GroupName + "/Role":
+		obj = &rbacv1.Role{}
+	case rbacv1.GroupName + "/ClusterRole":
+		obj = &rbacv1.ClusterRole{}
+	case rbacv1.GroupName + "/RoleBinding":
+		obj = &rbacv1.RoleBinding{}
+	case rbacv1.GroupName + "/ClusterRoleBinding":
+		obj = &rbacv1.ClusterRoleBinding{}
+	default:
+		return nil, fmt.Errorf("unsupported managed object %s.%s", ref.Kind, ref.Group)
+	}
+	obj.SetName(ref.Name)
+	obj.SetNamespace(ref.Namespace)
+	return obj, nil
+}
+
+// returns all objects that a claim manages on the target cluster,
+// as derived from its spec. Used in addition to the inventory in status,
+// because the inventory may not have been persisted for every created object.
+func expectedManagedObjects(claim *permissionsv1alpha1.PermissionClaim) []permissionsv1alpha1.ManagedObjectReference {
+	clusterMeta := metav1.ObjectMeta{Name: clusterScopedName(claim)}
+	refs := []permissionsv1alpha1.ManagedObjectReference{
+		managedObjectRef(&rbacv1.ClusterRoleBinding{ObjectMeta: clusterMeta}),
+		managedObjectRef(&rbacv1.ClusterRole{ObjectMeta: clusterMeta}),
+	}
+	for _, nsRules := range desiredNamespaceRules(claim) {
+		nsMeta := metav1.ObjectMeta{Name: claim.Name, Namespace: nsRules.namespace}
+		refs = append(refs,
+			managedObjectRef(&rbacv1.RoleBinding{ObjectMeta: nsMeta}),
+			managedObjectRef(&rbacv1.Role{ObjectMeta: nsMeta}),
+		)
+	}
+	for _, name := range claim.Spec.ClusterRoleRefs {
+		refs = append(refs, managedObjectRef(&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterRoleRefBindingName(claim, name)},
+		}))
+	}
+	for _, name := range claim.Spec.RoleRefs {
+		for _, ns := range targetNamespaces(claim) {
+			refs = append(refs, managedObjectRef(&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: roleRefBindingName(claim, name), Namespace: ns},
+			}))
+		}
+	}
+	homeMeta := metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Spec.Namespace}
+	return append(refs,
+		managedObjectRef(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name: claim.Name + "-token", Namespace: claim.Spec.Namespace,
+		}}),
+		managedObjectRef(&corev1.ServiceAccount{ObjectMeta: homeMeta}),
+	)
+}
+
+// returns objects that previous versions of this operator created,
+// before the inventory of managed objects was introduced.
+func legacyManagedObjects(claim *permissionsv1alpha1.PermissionClaim) []permissionsv1alpha1.ManagedObjectReference {
+	clusterMeta := metav1.ObjectMeta{Name: claim.Name}
+	return []permissionsv1alpha1.ManagedObjectReference{
+		managedObjectRef(&rbacv1.ClusterRoleBinding{ObjectMeta: clusterMeta}),
+		managedObjectRef(&rbacv1.ClusterRole{ObjectMeta: clusterMeta}),
+	}
+}
+
+// pruneManagedObjects deletes objects that the claim created on the target cluster,
+// but that are no longer part of its desired state.
+func (c *PermissionClaimController) pruneManagedObjects(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	expected := expectedManagedObjects(claim)
+	candidates := claim.Status.ManagedObjects
+	for _, ref := range legacyManagedObjects(claim) {
+		candidates = appendManagedObjectRef(candidates, ref)
+	}
+
+	for _, ref := range candidates {
+		if containsManagedObjectRef(expected, ref) {
+			continue
+		}
+
+		obj, err := objectForManagedObjectRef(ref)
+		if err != nil {
+			return err
+		}
+		err = c.targetClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("getting %s: %w", ref.Kind, err)
+		}
+		if err == nil && c.ownerStrategy.IsOwner(claim, obj) {
+			if err := c.targetClient.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("deleting %s: %w", ref.Kind, err)
+			}
+			c.recorder.Eventf(claim, corev1.EventTypeNormal, eventReasonPruned,
+				"Deleted %s %s from the target cluster, it is no longer part of the claim", ref.Kind, objectName(obj))
+		}
+		forgetManagedObject(claim, ref)
+	}
+	return nil
+}
+
+func containsManagedObjectRef(
+	refs []permissionsv1alpha1.ManagedObjectReference, ref permissionsv1alpha1.ManagedObjectReference,
+) bool {
+	for _, existing := range refs {
+		if existing == ref {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileKubeconfigSecret combines the kubeconfigs issued for the individual TargetClusters
+// into a single kubeconfig with one context per TargetCluster.
+func (c *ClusterSetPermissionClaimController) reconcileKubeconfigSecret(
+	ctx context.Context, set *permissionsv1alpha1.ClusterSetPermissionClaim,
+	statuses []permissionsv1alpha1.ClusterSetTargetClusterStatus,
+) error {
+	if set.Spec.Kubeconfig == nil {
+		set.Status.KubeconfigContexts = nil
+		return nil
+	}
+
+	kubeconfigs := map[string]*clientcmdapi.Config{}
+	for _, status := range statuses {
+		kubeconfig, err := c.claimKubeconfig(ctx, set.Namespace, status)
+		if err != nil {
+			return fmt.Errorf("TargetCluster %s: %w", status.Name, err)
+		}
+		if kubeconfig != nil {
+			kubeconfigs[status.Name] = kubeconfig
+		}
+	}
+	combined, err := combineKubeconfigs(kubeconfigs, set.Spec.Kubeconfig.CurrentContext)
+	if err != nil {
+		return err
+	}
+	kubeconfigYaml, err := clientcmd.Write(*combined)
+	if err != nil {
+		return fmt.Errorf("rendering kubeconfig: %w", err)
+	}
+
+	desiredSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      set.Spec.Kubeconfig.SecretName,
+			Namespace: set.Namespace,
+		},
+		Data: map[string][]byte{
+			corev1.ServiceAccountKubeconfigKey: kubeconfigYaml,
+		},
+	}
+	if err := controllerutil.SetControllerReference(set, desiredSecret, c.scheme); err != nil {
+		return fmt.Errorf("set controller-reference: %w", err)
+	}
+
+	existingSecret := &corev1.Secret{}
+	err = c.client.Get(ctx, client.ObjectKeyFromObject(desiredSecret), existingSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting Secret: %w", err)
+	}
+	if errors.IsNotFound(err) {
+		if err := c.client.Create(ctx, desiredSecret); err != nil {
+			return fmt.Errorf("creating Secret: %w", err)
+		}
+	} else if !metav1.IsControlledBy(existingSecret, set) {
+		return fmt.Errorf("Secret %s already exists and is not controlled by this ClusterSetPermissionClaim",
+			existingSecret.Name)
+	} else if !equality.Semantic.DeepEqual(desiredSecret.Data, existingSecret.Data) {
+		existingSecret.Data = desiredSecret.Data
+		if err := c.client.Update(ctx, existingSecret); err != nil {
+			return fmt.Errorf("updating Secret: %w", err)
+		}
+	}
+
+	set.Status.KubeconfigContexts = nil
+	for name := range combined.Contexts {
+		set.Status.KubeconfigContexts = append(set.Status.KubeconfigContexts, name)
+	}
+	sort.Strings(set.Status.KubeconfigContexts)
+	return nil
+}
+
+// claimKubeconfig returns the kubeconfig issued for the PermissionClaim of a single TargetCluster.
+// Returns nil, if no kubeconfig has been issued yet.
+func (c *ClusterSetPermissionClaimController) claimKubeconfig(
+	ctx context.Context, namespace string,
+	status permissionsv1alpha1.ClusterSetTargetClusterStatus,
+) (*clientcmdapi.Config, error) {
+	secret := &corev1.Secret{}
+	err := c.client.Get(ctx, client.ObjectKey{
+		Name:      status.SecretName,
+		Namespace: namespace,
+	}, secret)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting kubeconfig Secret: %w", err)
+	}
+	// Never hand out credentials that have not been issued for the claim.
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil || owner.Kind != "PermissionClaim" || owner.Name != status.ClaimName {
+		return nil, nil
+	}
+
+	data, ok := secret.Data[corev1.ServiceAccountKubeconfigKey]
+	if !ok {
+		return nil, nil
+	}
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+	return kubeconfig, nil
+}
+
+// combineKubeconfigs merges the current context of each kubeconfig into a single kubeconfig.
+// Contexts, clusters and users are named after the key of the kubeconfig in the given map.
+// currentContext is used as current-context if present, otherwise the first context is used.
+func combineKubeconfigs(
+	kubeconfigs map[string]*clientcmdapi.Config, currentContext string,
+) (*clientcmdapi.Config, error) {
+	names := make([]string, 0, len(kubeconfigs))
+	for name := range kubeconfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	combined := clientcmdapi.NewConfig()
+	for _, name := range names {
+		kubeconfig := kubeconfigs[name]
+		contextName := kubeconfig.CurrentContext
+		if len(contextName) == 0 && len(kubeconfig.Contexts) == 1 {
+			for n := range kubeconfig.Contexts {
+				contextName = n
+			}
+		}
+		kubeContext, ok := kubeconfig.Contexts[contextName]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of TargetCluster %s has no current-context", name)
+		}
+		cluster, ok := kubeconfig.Clusters[kubeContext.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of TargetCluster %s is missing cluster %q", name, kubeContext.Cluster)
+		}
+		authInfo, ok := kubeconfig.AuthInfos[kubeContext.AuthInfo]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of TargetCluster %s is missing user %q", name, kubeContext.AuthInfo)
+		}
+
+		combined.Clusters[name] = cluster.DeepCopy()
+		combined.AuthInfos[name] = authInfo.DeepCopy()
+		combined.Contexts[name] = &clientcmdapi.Context{
+			Cluster:   name,
+			AuthInfo:  name,
+			Namespace: kubeContext.Namespace,
+		}
+	}
+
+	if _, ok := combined.Contexts[currentContext]; ok {
+		combined.CurrentContext = currentContext
+	} else if len(names) > 0 {
+		combined.CurrentContext = names[0]
+	}
+	return combined, nil
+}
+
+// enqueueClusterSetForClaimSecret maps events of kubeconfig Secrets of PermissionClaims
+// to the ClusterSetPermissionClaim controlling the PermissionClaim.
+func (c *ClusterSetPermissionClaimController) enqueueClusterSetForClaimSecret(obj client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "PermissionClaim" {
+		return nil
+	}
+
+	claim := &permissionsv1alpha1.PermissionClaim{}
+	if err := c.client.Get(context.Background(), client.ObjectKey{
+		Name:      owner.Name,
+		Namespace: obj.GetNamespace(),
+	}, claim); err != nil {
+		return nil
+	}
+	setOwner := metav1.GetControllerOf(claim)
+	if setOwner == nil || setOwner.Kind != "ClusterSetPermissionClaim" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: client.ObjectKey{
+			Name:      setOwner.Name,
+			Namespace: claim.Namespace,
+		},
+	}}
+}
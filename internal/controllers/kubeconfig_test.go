@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// returns a kubeconfig with a single context, as rendered for PermissionClaims.
+func testKubeconfig(server, token, namespace string) *clientcmdapi.Config {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["cluster"] = &clientcmdapi.Cluster{Server: server}
+	kubeconfig.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: token}
+	kubeconfig.Contexts["default"] = &clientcmdapi.Context{
+		Cluster: "cluster", AuthInfo: "user", Namespace: namespace,
+	}
+	kubeconfig.CurrentContext = "default"
+	return kubeconfig
+}
+
+func TestCombineKubeconfigs(t *testing.T) {
+	kubeconfigs := map[string]*clientcmdapi.Config{
+		"eu-1": testKubeconfig("https://eu-1", "token-eu-1", "ns"),
+		"us-1": testKubeconfig("https://us-1", "token-us-1", "ns"),
+	}
+	withoutCurrentContext := testKubeconfig("https://eu-1", "token-eu-1", "ns")
+	withoutCurrentContext.CurrentContext = ""
+	missingUser := testKubeconfig("https://eu-1", "token-eu-1", "ns")
+	delete(missingUser.AuthInfos, "user")
+
+	tests := []struct {
+		name               string
+		kubeconfigs        map[string]*clientcmdapi.Config
+		currentContext     string
+		wantContexts       []string
+		wantCurrentContext string
+		wantErr            bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:               "defaults to the first context",
+			kubeconfigs:        kubeconfigs,
+			wantContexts:       []string{"eu-1", "us-1"},
+			wantCurrentContext: "eu-1",
+		},
+		{
+			name:               "current context",
+			kubeconfigs:        kubeconfigs,
+			currentContext:     "us-1",
+			wantContexts:       []string{"eu-1", "us-1"},
+			wantCurrentContext: "us-1",
+		},
+		{
+			name:               "unknown current context",
+			kubeconfigs:        kubeconfigs,
+			currentContext:     "ap-1",
+			wantContexts:       []string{"eu-1", "us-1"},
+			wantCurrentContext: "eu-1",
+		},
+		{
+			name:               "single context without current-context",
+			kubeconfigs:        map[string]*clientcmdapi.Config{"eu-1": withoutCurrentContext},
+			wantContexts:       []string{"eu-1"},
+			wantCurrentContext: "eu-1",
+		},
+		{
+			name:        "missing user",
+			kubeconfigs: map[string]*clientcmdapi.Config{"eu-1": missingUser},
+			wantErr:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			combined, err := combineKubeconfigs(test.kubeconfigs, test.currentContext)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if combined.CurrentContext != test.wantCurrentContext {
+				t.Errorf("current-context = %q, want %q", combined.CurrentContext, test.wantCurrentContext)
+			}
+			var contexts []string
+			for name := range combined.Contexts {
+				contexts = append(contexts, name)
+			}
+			if len(contexts) != len(test.wantContexts) {
+				t.Fatalf("contexts = %v, want %v", contexts, test.wantContexts)
+			}
+			for _, name := range test.wantContexts {
+				source := test.kubeconfigs[name]
+				want := &clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: "ns"}
+				if got := combined.Contexts[name]; !reflect.DeepEqual(got, want) {
+					t.Errorf("context %s = %+v, want %+v", name, got, want)
+				}
+				if got := combined.Clusters[name]; !reflect.DeepEqual(got, source.Clusters["cluster"]) {
+					t.Errorf("cluster %s = %+v, want %+v", name, got, source.Clusters["cluster"])
+				}
+				if got := combined.AuthInfos[name]; !reflect.DeepEqual(got, source.AuthInfos["user"]) {
+					t.Errorf("user %s = %+v, want %+v", name, got, source.AuthInfos["user"])
+				}
+			}
+		})
+	}
+}
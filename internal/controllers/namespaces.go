@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// targetNamespaces returns all namespaces the claim requests
+// namespace-scoped permissions in, starting with the home namespace.
+// Namespaces matched by the namespace selector are taken from status,
+// see resolveNamespaceSelector.
+func targetNamespaces(claim *permissionsv1alpha1.PermissionClaim) []string {
+	namespaces := []string{claim.Spec.Namespace}
+	seen := map[string]struct{}{claim.Spec.Namespace: {}}
+	for _, list := range [][]string{claim.Spec.Namespaces, claim.Status.MatchedNamespaces} {
+		for _, ns := range list {
+			if _, ok := seen[ns]; ok {
+				continue
+			}
+			seen[ns] = struct{}{}
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// namespaceRules are the namespace-scoped permissions of a claim in a single namespace.
+type namespaceRules struct {
+	namespace string
+	rules     []rbacv1.PolicyRule
+}
+
+// desiredNamespaceRules returns the permissions per namespace,
+// merging .spec.rules for all target namespaces with .spec.namespacedRules.
+func desiredNamespaceRules(claim *permissionsv1alpha1.PermissionClaim) []namespaceRules {
+	var desired []namespaceRules
+	index := map[string]int{}
+	for _, ns := range targetNamespaces(claim) {
+		index[ns] = len(desired)
+		desired = append(desired, namespaceRules{
+			namespace: ns,
+			rules:     append([]rbacv1.PolicyRule{}, claim.Spec.Rules...),
+		})
+	}
+	for _, nsRules := range claim.Spec.NamespacedRules {
+		if i, ok := index[nsRules.Namespace]; ok {
+			desired[i].rules = append(desired[i].rules, nsRules.Rules...)
+			continue
+		}
+		index[nsRules.Namespace] = len(desired)
+		desired = append(desired, namespaceRules{
+			namespace: nsRules.Namespace,
+			rules:     append([]rbacv1.PolicyRule{}, nsRules.Rules...),
+		})
+	}
+	return desired
+}
+
+// resolveNamespaceSelector updates the list of namespaces
+// matching the claims namespace selector in status.
+func (c *PermissionClaimController) resolveNamespaceSelector(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	if claim.Spec.NamespaceSelector == nil {
+		claim.Status.MatchedNamespaces = nil
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(claim.Spec.NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("parsing namespace selector: %w", err)
+	}
+	namespaceList := &corev1.NamespaceList{}
+	if err := c.targetClient.List(ctx, namespaceList, client.MatchingLabelsSelector{
+		Selector: selector,
+	}); err != nil {
+		return fmt.Errorf("listing namespaces: %w", err)
+	}
+
+	var matched []string
+	for _, ns := range namespaceList.Items {
+		if !ns.DeletionTimestamp.IsZero() {
+			// RBAC can't be created in terminating namespaces.
+			continue
+		}
+		matched = append(matched, ns.Name)
+	}
+	sort.Strings(matched)
+	claim.Status.MatchedNamespaces = matched
+	return nil
+}
+
+// enqueueClaimsWithNamespaceSelector maps Namespace events
+// on the given target cluster to all PermissionClaims on it selecting namespaces by label.
+func (c *PermissionClaimController) enqueueClaimsWithNamespaceSelector(cluster string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		claimList := &permissionsv1alpha1.PermissionClaimList{}
+		if err := c.client.List(context.Background(), claimList); err != nil {
+			c.log.Error(err, "listing PermissionClaims for Namespace event", "Namespace", obj.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, claim := range claimList.Items {
+			if claim.Spec.NamespaceSelector == nil ||
+				targetClusterName(&claim) != cluster {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&claim),
+			})
+		}
+		return requests
+	}
+}
+
+// reconcileNamespaces ensures a Role and RoleBinding in every namespace the claim has permissions in.
+// A failing namespace does not prevent the other namespaces from being reconciled,
+// errors are reported per namespace in status and returned as aggregates for Roles and RoleBindings.
+func (c *PermissionClaimController) reconcileNamespaces(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	sa *corev1.ServiceAccount,
+) (rolesErr, bindingsErr error) {
+	var (
+		statuses    []permissionsv1alpha1.PermissionClaimNamespaceStatus
+		roleErrs    []error
+		bindingErrs []error
+	)
+	for _, nsRules := range desiredNamespaceRules(claim) {
+		status := permissionsv1alpha1.PermissionClaimNamespaceStatus{Name: nsRules.namespace}
+		roleErr, bindingErr := c.reconcileNamespace(ctx, claim, nsRules, sa)
+		switch {
+		case roleErr != nil:
+			status.Message = roleErr.Error()
+			roleErrs = append(roleErrs, fmt.Errorf("namespace %s: %w", nsRules.namespace, roleErr))
+		case bindingErr != nil:
+			status.Message = bindingErr.Error()
+			bindingErrs = append(bindingErrs, fmt.Errorf("namespace %s: %w", nsRules.namespace, bindingErr))
+		default:
+			status.Ready = true
+		}
+		statuses = append(statuses, status)
+	}
+	claim.Status.Namespaces = statuses
+	return utilerrors.NewAggregate(roleErrs), utilerrors.NewAggregate(bindingErrs)
+}
+
+func (c *PermissionClaimController) reconcileNamespace(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	nsRules namespaceRules, sa *corev1.ServiceAccount,
+) (roleErr, bindingErr error) {
+	role, err := c.reconcileRole(ctx, claim, nsRules.namespace, nsRules.rules)
+	if err != nil {
+		return fmt.Errorf("reconciling Role: %w", err), nil
+	}
+
+	if err := c.reconcileRoleBinding(ctx, claim, claim.Name, role.Namespace, role, sa); err != nil {
+		return nil, fmt.Errorf("reconciling RoleBinding: %w", err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Prefix for cluster-scoped objects created for PermissionClaims.
+const clusterScopedNamePrefix = "permission-claim"
+
+// Length of the hash suffix of truncated names.
+const nameHashLength = 16
+
+// clusterScopedName returns the name for cluster-scoped objects created for the claim.
+// PermissionClaims from different namespaces may share a name,
+// so the name is qualified with the claims namespace.
+func clusterScopedName(claim *permissionsv1alpha1.PermissionClaim) string {
+	return truncateName(
+		clusterScopedNamePrefix+":"+claim.Namespace+":"+claim.Name,
+		validation.DNS1123SubdomainMaxLength)
+}
+
+// truncateName shortens names exceeding maxLength,
+// replacing the excess with a hash of the full name to keep them unique.
+func truncateName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	suffix := "-" + hex.EncodeToString(hash[:])[:nameHashLength]
+	return name[:maxLength-len(suffix)] + suffix
+}
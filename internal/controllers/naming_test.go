@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		want      string
+	}{
+		{name: "short", input: "my-claim", maxLength: 20, want: "my-claim"},
+		{name: "exact length", input: "my-claim", maxLength: 8, want: "my-claim"},
+		{
+			name:      "truncated",
+			input:     "my-claim-with-a-long-name",
+			maxLength: 24,
+			want:      "my-clai-67e2e36155f67046",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := truncateName(test.input, test.maxLength)
+			if got != test.want {
+				t.Errorf("truncateName() = %q, want %q", got, test.want)
+			}
+			if len(got) > test.maxLength {
+				t.Errorf("len(truncateName()) = %d, exceeds %d", len(got), test.maxLength)
+			}
+		})
+	}
+
+	t.Run("unique", func(t *testing.T) {
+		a := truncateName(long+"-a", 253)
+		b := truncateName(long+"-b", 253)
+		if a == b {
+			t.Errorf("truncated names collide: %q", a)
+		}
+		if len(a) != 253 {
+			t.Errorf("len(truncateName()) = %d, want 253", len(a))
+		}
+	})
+}
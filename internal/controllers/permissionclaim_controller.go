@@ -1,11 +1,15 @@
 package controllers
 
 import (
+	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/escalation"
 	"github.com/thetechnick/permission-claim-operator/internal/ownerhandling"
+	"github.com/thetechnick/permission-claim-operator/internal/targetclusters"
 	"golang.org/x/net/context"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -14,49 +18,79 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/strings/slices"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type PermissionClaimController struct {
-	log    logr.Logger
-	client client.Client
-	scheme *runtime.Scheme
+	log      logr.Logger
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
 
-	baseKubeconfig *clientcmdapi.Config
-	targetClient   client.Client
-	targetCache    cache.Cache
+	targetClusters *targetclusters.Pool
 	ownerStrategy  ownerStrategy
+
+	// Clients of the target cluster of the claim currently reconciled,
+	// set on the copy returned by withTargetCluster.
+	baseKubeconfig        *clientcmdapi.Config
+	targetClient          client.Client
+	targetServiceAccounts corev1client.ServiceAccountsGetter
+
+	// claims are only reconciled onto the target cluster
+	// after their spec has been approved.
+	requireApproval bool
+	// claims are only reconciled if the user who last changed them
+	// holds all requested permissions on the target cluster.
+	checkRequester bool
+
+	riskAnalyzer *escalation.Analyzer
+	// claims with an escalation risk at or above this severity are not reconciled.
+	// Empty disables blocking.
+	blockRiskSeverity permissionsv1alpha1.RiskSeverity
 }
 
 func NewPermissionClaimController(
 	log logr.Logger,
 	client client.Client,
 	scheme *runtime.Scheme,
-	baseKubeconfig *clientcmdapi.Config,
-	targetClient client.Client,
-	targetCache cache.Cache,
+	recorder record.EventRecorder,
+	targetClusters *targetclusters.Pool,
+	requireApproval bool,
+	checkRequester bool,
+	riskAnalyzer *escalation.Analyzer,
+	blockRiskSeverity permissionsv1alpha1.RiskSeverity,
 ) *PermissionClaimController {
 	return &PermissionClaimController{
-		log:    log,
-		client: client,
-		scheme: scheme,
+		log:      log,
+		client:   client,
+		scheme:   scheme,
+		recorder: recorder,
 
-		baseKubeconfig: baseKubeconfig,
-		targetClient:   targetClient,
-		targetCache:    targetCache,
+		targetClusters: targetClusters,
 		ownerStrategy:  ownerhandling.Annotation,
+
+		requireApproval: requireApproval,
+		checkRequester:  checkRequester,
+
+		riskAnalyzer:      riskAnalyzer,
+		blockRiskSeverity: blockRiskSeverity,
 	}
 }
 
 type ownerStrategy interface {
+	IsOwner(owner, obj metav1.Object) bool
 	SetControllerReference(owner, obj metav1.Object, scheme *runtime.Scheme) error
 	EnqueueRequestForOwner(ownerType client.Object, isController bool) handler.EventHandler
 }
@@ -70,51 +104,205 @@ func (c *PermissionClaimController) Reconcile(ctx context.Context, req ctrl.Requ
 
 	if !claim.GetDeletionTimestamp().IsZero() {
 		// ObjectSet was deleted.
-		return ctrl.Result{}, c.handleDeletion(ctx, claim)
+		cluster, err := c.targetClusterForCleanup(ctx, claim)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if cluster == nil {
+			// TargetCluster is gone, nothing left to clean up.
+			return ctrl.Result{}, c.removeCleanupFinalizer(ctx, claim)
+		}
+		return ctrl.Result{}, c.withTargetCluster(cluster).handleDeletion(ctx, claim)
 	}
 
 	if err := c.ensureCacheFinalizer(ctx, claim); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	role, err := c.reconcileRole(ctx, claim)
+	// Status is patched after every reconcile,
+	// so conditions also explain failed and incomplete attempts.
+	original := claim.DeepCopy()
+	var res ctrl.Result
+	cluster, err := c.targetClusters.Get(targetClusterName(claim))
+	if err != nil {
+		err = targetClusterUnavailable(claim, err)
+	} else if retryAfter, reachable := c.checkTargetClusterReachable(ctx, claim, cluster); !reachable {
+		// Not an error, so the backoff is not compounded by the rate limiter of the controller.
+		log.Info("target cluster unreachable", "retryAfter", retryAfter)
+		res = ctrl.Result{RequeueAfter: retryAfter}
+	} else {
+		res, err = c.withTargetCluster(cluster).reconcile(ctx, claim)
+	}
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling Role: %w", err)
+		c.recorder.Event(claim, corev1.EventTypeWarning, eventReasonReconcileFailed, err.Error())
+	}
+	claim.Status.ObservedGeneration = claim.Generation
+	if perr := c.client.Status().Patch(ctx, claim, client.MergeFrom(original)); perr != nil &&
+		!errors.IsNotFound(perr) {
+		if err != nil {
+			log.Error(perr, "patching status")
+			return res, err
+		}
+		return res, fmt.Errorf("patching status: %w", perr)
 	}
+	return res, err
+}
 
-	clusterRole, err := c.reconcileClusterRole(ctx, claim)
+func (c *PermissionClaimController) reconcile(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (ctrl.Result, error) {
+	log := c.log.WithValues("PermissionClaim", client.ObjectKeyFromObject(claim).String())
+
+	if checkExpiry(claim, time.Now()) {
+		log.Info("claim expired, revoking permissions")
+		return ctrl.Result{}, c.handleExpiry(ctx, claim)
+	}
+	// Requeue exactly when the claim expires,
+	// if nothing else triggers a reconcile earlier.
+	expiryRes := ctrl.Result{RequeueAfter: expiryRequeueAfter(claim, time.Now())}
+
+	revoked, err := c.revokeCredentials(ctx, claim)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling ClusterRole: %w", err)
+		return ctrl.Result{}, fmt.Errorf("revoking credentials: %w", err)
+	}
+	if revoked {
+		// Removing the annotation triggers another reconcile to issue new credentials.
+		return ctrl.Result{}, nil
 	}
 
-	sa, err := c.reconcileServiceAccount(ctx, claim)
+	if claim.Spec.Suspended {
+		log.Info("claim suspended, removing bindings")
+		return expiryRes, c.handleSuspension(ctx, claim)
+	}
+	meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimSuspended)
+
+	if err := c.resolveNamespaceSelector(ctx, claim); err != nil {
+		return ctrl.Result{}, fmt.Errorf("resolving namespace selector: %w", err)
+	}
+
+	if !c.assessEscalationRisk(claim) {
+		// Like policy denial, existing permissions are left as they are.
+		log.Info("blocked due to escalation risk",
+			"severity", claim.Status.RiskAssessment.Severity)
+		return expiryRes, nil
+	}
+
+	allowed, err := c.evaluatePolicies(ctx, claim)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling ServiceAccount: %w", err)
+		return ctrl.Result{}, fmt.Errorf("evaluating policies: %w", err)
+	}
+	if !allowed {
+		// Existing permissions are not touched,
+		// until the claim conforms to policy again.
+		log.Info("denied by policy")
+		return expiryRes, nil
 	}
 
-	if err := c.reconcileRoleBinding(ctx, claim, role, sa); err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling RoleBinding: %w", err)
+	authorized, err := c.checkRequesterPermissions(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("checking requester permissions: %w", err)
+	}
+	if !authorized {
+		log.Info("requester lacks requested permissions")
+		return expiryRes, nil
 	}
 
-	if err := c.reconcileClusterRoleBinding(ctx, claim, clusterRole, sa); err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling ClusterRoleBinding: %w", err)
+	approved, err := c.checkApproval(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("checking approval: %w", err)
+	}
+	if !approved {
+		// Existing permissions stay as last approved,
+		// until the new spec is approved.
+		// Denied claims let their tokens expire instead.
+		log.Info("waiting for approval")
+		return c.keepCredentials(ctx, claim, expiryRes)
 	}
 
-	tokenSecret, err := c.reconcileTokenSecret(ctx, claim, sa)
+	clusterRole, err := c.reconcileClusterRole(ctx, claim)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconciling token Secret: %w", err)
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimRolesReady,
+			fmt.Errorf("reconciling ClusterRole: %w", err))
 	}
 
-	if len(tokenSecret.Data[corev1.ServiceAccountTokenKey]) == 0 {
-		log.Info("waiting for secrets token field to be populated")
-		return ctrl.Result{}, nil
+	sa, err := c.reconcileServiceAccount(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimServiceAccountReady,
+			fmt.Errorf("reconciling ServiceAccount: %w", err))
+	}
+	setReadinessCondition(claim, permissionsv1alpha1.PermissionClaimServiceAccountReady, nil)
+
+	// Failing namespaces are reported in status,
+	// but should not block the rest of the claim.
+	rolesErr, bindingsErr := c.reconcileNamespaces(ctx, claim, sa)
+	setReadinessCondition(claim, permissionsv1alpha1.PermissionClaimRolesReady, rolesErr)
+
+	if err := c.reconcileClusterRoleBinding(
+		ctx, claim, clusterScopedName(claim), clusterRole, sa); err != nil {
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimBindingsReady,
+			fmt.Errorf("reconciling ClusterRoleBinding: %w", err))
 	}
 
-	if err := c.reconcileKubeconfigSecret(ctx, claim, tokenSecret); err != nil {
-		return ctrl.Result{}, fmt.Errorf("reconcile Kubeconfig Secret: %w", err)
+	if err := c.reconcileRoleRefs(ctx, claim, sa); err != nil {
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimBindingsReady,
+			fmt.Errorf("reconciling role references: %w", err))
 	}
+	setReadinessCondition(claim, permissionsv1alpha1.PermissionClaimBindingsReady, bindingsErr)
 
-	return ctrl.Result{}, c.client.Status().Update(ctx, claim)
+	if err := c.pruneManagedObjects(ctx, claim); err != nil {
+		return ctrl.Result{}, fmt.Errorf("pruning stale objects: %w", err)
+	}
+
+	token, err := c.reconcileCredentials(ctx, claim, sa)
+	if err != nil {
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimCredentialsReady,
+			fmt.Errorf("reconciling credentials: %w", err))
+	}
+
+	if len(token) == 0 {
+		log.Info("waiting for secrets token field to be populated")
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimKubeconfigOutOfDate,
+			Status:             metav1.ConditionTrue,
+			Reason:             "WaitingForCredentials",
+			Message:            "Waiting for the ServiceAccount token to be issued.",
+			ObservedGeneration: claim.Generation,
+		})
+		setReadinessConditionFalse(claim, permissionsv1alpha1.PermissionClaimCredentialsReady,
+			"WaitingForToken", "Waiting for the ServiceAccount token to be issued.")
+		updateBoundCondition(claim)
+		return expiryRes, nil
+	}
+
+	if err := c.reconcileKubeconfigSecret(ctx, claim, token); err != nil {
+		reason := "UpdateFailed"
+		if isNotOwned(err) {
+			reason = "NotOwned"
+		}
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimKubeconfigOutOfDate,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            err.Error(),
+			ObservedGeneration: claim.Generation,
+		})
+		return ctrl.Result{}, stepFailed(claim, permissionsv1alpha1.PermissionClaimCredentialsReady,
+			fmt.Errorf("reconcile Kubeconfig Secret: %w", err))
+	}
+	setReadinessCondition(claim, permissionsv1alpha1.PermissionClaimCredentialsReady, nil)
+	updateBoundCondition(claim)
+
+	res := ctrl.Result{RequeueAfter: minRequeueAfter(
+		credentialsRequeueAfter(claim, time.Now()),
+		expiryRes.RequeueAfter,
+	)}
+	if err := utilerrors.NewAggregate([]error{rolesErr, bindingsErr}); err != nil {
+		return res, fmt.Errorf("reconciling namespaces: %w", err)
+	}
+	// Later changes to objects on the target cluster
+	// without a change to the spec are drift.
+	claim.Status.AppliedGeneration = claim.Generation
+	return res, nil
 }
 
 func (c *PermissionClaimController) reconcileTokenSecret(
@@ -141,35 +329,34 @@ func (c *PermissionClaimController) reconcileTokenSecret(
 		if err := c.targetClient.Create(ctx, newSecret); err != nil {
 			return nil, fmt.Errorf("creating token Secret: %w", err)
 		}
+		recordManagedObject(claim, managedObjectRef(newSecret))
+		c.recordCreated(claim, newSecret)
 		return newSecret, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("getting token Secret: %w", err)
 	}
 
+	// The token of a foreign Secret may belong to any ServiceAccount.
+	if !c.ownerStrategy.IsOwner(claim, existingSecret) ||
+		existingSecret.Type != corev1.SecretTypeServiceAccountToken ||
+		existingSecret.Annotations[corev1.ServiceAccountNameKey] != sa.Name {
+		return nil, newNotOwnedError("Secret", existingSecret)
+	}
+	recordManagedObject(claim, managedObjectRef(existingSecret))
 	return existingSecret, nil
 }
 
 func (c *PermissionClaimController) reconcileKubeconfigSecret(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
-	tokenSecret *corev1.Secret,
+	token []byte,
 ) error {
-	token := tokenSecret.Data[corev1.ServiceAccountTokenKey]
-	newKubeconfig := c.baseKubeconfig.DeepCopy()
-
-	// replace all auth with the SA token:
-	for i := range newKubeconfig.AuthInfos {
-		newKubeconfig.AuthInfos[i] = &clientcmdapi.AuthInfo{
-			Token: string(token),
-		}
-	}
-
-	kubeconfigYaml, err := clientcmd.Write(*newKubeconfig)
+	kubeconfigYaml, err := c.renderKubeconfig(token)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	newSecret := &corev1.Secret{
+	desiredSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      claim.Spec.SecretName,
 			Namespace: claim.Namespace,
@@ -178,21 +365,108 @@ func (c *PermissionClaimController) reconcileKubeconfigSecret(
 			corev1.ServiceAccountKubeconfigKey: kubeconfigYaml,
 		},
 	}
-	if err := controllerutil.SetControllerReference(claim, newSecret, c.scheme); err != nil {
+	if err := controllerutil.SetControllerReference(claim, desiredSecret, c.scheme); err != nil {
 		return fmt.Errorf("set controller-reference: %w", err)
 	}
-	if err := c.client.Create(ctx, newSecret); err != nil && !errors.IsAlreadyExists(err) {
-		return fmt.Errorf("creating Secret: %w", err)
+
+	existingSecret := &corev1.Secret{}
+	err = c.client.Get(ctx, client.ObjectKeyFromObject(desiredSecret), existingSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting Secret: %w", err)
+	}
+	if errors.IsNotFound(err) {
+		if err := c.client.Create(ctx, desiredSecret); err != nil {
+			return fmt.Errorf("creating Secret: %w", err)
+		}
+		c.setKubeconfigUpToDate(claim)
+		return nil
+	}
+
+	// existing Secret
+	// Never hand out credentials via a Secret someone else manages.
+	if !metav1.IsControlledBy(existingSecret, claim) {
+		return newNotOwnedError("Secret", existingSecret)
+	}
+	if !equality.Semantic.DeepEqual(desiredSecret.Data, existingSecret.Data) {
+		// Rotated tokens are expected to change the Secret,
+		// anything else was changed by someone else.
+		drifted, err := c.kubeconfigDrifted(existingSecret)
+		if err != nil {
+			return err
+		}
+		existingSecret.Data = desiredSecret.Data
+		if err := c.client.Update(ctx, existingSecret); err != nil {
+			return fmt.Errorf("updating Secret: %w", err)
+		}
+		if drifted {
+			c.recordDriftRepaired(claim, existingSecret)
+		}
+	}
+	c.setKubeconfigUpToDate(claim)
+	return nil
+}
+
+// kubeconfigDrifted returns true if the data of the given kubeconfig Secret
+// is not what the operator renders for the token embedded in it.
+func (c *PermissionClaimController) kubeconfigDrifted(secret *corev1.Secret) (bool, error) {
+	data := secret.Data[corev1.ServiceAccountKubeconfigKey]
+	rendered, err := c.renderKubeconfig(kubeconfigToken(data))
+	if err != nil {
+		return false, err
+	}
+	return len(secret.Data) != 1 || !bytes.Equal(rendered, data), nil
+}
+
+// deleteKubeconfigSecret deletes the kubeconfig Secret of the claim,
+// if it is controlled by the claim.
+func (c *PermissionClaimController) deleteKubeconfigSecret(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	secret := &corev1.Secret{}
+	err := c.client.Get(ctx, client.ObjectKey{
+		Name:      claim.Spec.SecretName,
+		Namespace: claim.Namespace,
+	}, secret)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting kubeconfig Secret: %w", err)
+	}
+	if !metav1.IsControlledBy(secret, claim) {
+		return nil
 	}
+	if err := c.client.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting kubeconfig Secret: %w", err)
+	}
+	return nil
+}
 
+func (c *PermissionClaimController) setKubeconfigUpToDate(claim *permissionsv1alpha1.PermissionClaim) {
 	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
-		Type:               permissionsv1alpha1.PermissionClaimBound,
-		Status:             metav1.ConditionTrue,
-		Reason:             "PermissionsEstablished",
+		Type:               permissionsv1alpha1.PermissionClaimKubeconfigOutOfDate,
+		Status:             metav1.ConditionFalse,
+		Reason:             "UpToDate",
 		ObservedGeneration: claim.Generation,
 	})
-	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseBound
-	return nil
+}
+
+// renders a new kubeconfig from the template, using the given token.
+func (c *PermissionClaimController) renderKubeconfig(token []byte) ([]byte, error) {
+	newKubeconfig := c.baseKubeconfig.DeepCopy()
+
+	// replace all auth with the SA token:
+	for i := range newKubeconfig.AuthInfos {
+		newKubeconfig.AuthInfos[i] = &clientcmdapi.AuthInfo{
+			Token: string(token),
+		}
+	}
+
+	kubeconfigYaml, err := clientcmd.Write(*newKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("rendering kubeconfig: %w", err)
+	}
+	return kubeconfigYaml, nil
 }
 
 func (c *PermissionClaimController) reconcileServiceAccount(
@@ -214,24 +488,32 @@ func (c *PermissionClaimController) reconcileServiceAccount(
 		if err := c.targetClient.Create(ctx, sa); err != nil {
 			return nil, fmt.Errorf("creating SA: %w", err)
 		}
+		recordManagedObject(claim, managedObjectRef(sa))
+		c.recordCreated(claim, sa)
 		return sa, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("getting SA: %w", err)
 	}
 
+	// Issuing tokens for a foreign ServiceAccount would hand out its permissions.
+	if !c.ownerStrategy.IsOwner(claim, existingSA) {
+		return nil, newNotOwnedError("ServiceAccount", existingSA)
+	}
+	recordManagedObject(claim, managedObjectRef(existingSA))
 	return existingSA, nil
 }
 
 func (c *PermissionClaimController) reconcileRole(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	namespace string, rules []rbacv1.PolicyRule,
 ) (*rbacv1.Role, error) {
 	desiredRole := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      claim.Name,
-			Namespace: claim.Spec.Namespace,
+			Namespace: namespace,
 		},
-		Rules: claim.Spec.Rules,
+		Rules: rules,
 	}
 	if err := c.ownerStrategy.SetControllerReference(claim, desiredRole, c.scheme); err != nil {
 		return nil, fmt.Errorf("set controller reference: %w", err)
@@ -246,17 +528,24 @@ func (c *PermissionClaimController) reconcileRole(
 		if err := c.targetClient.Create(ctx, desiredRole); err != nil {
 			return nil, err
 		}
+		recordManagedObject(claim, managedObjectRef(desiredRole))
+		c.recordCreated(claim, desiredRole)
 		return desiredRole, nil
 	}
 
 	// existing Role
+	if !c.ownerStrategy.IsOwner(claim, existingRole) {
+		return nil, newNotOwnedError("Role", existingRole)
+	}
 	if !equality.Semantic.DeepEqual(desiredRole.Rules, existingRole.Rules) {
 		existingRole.Rules = desiredRole.Rules
 		if err := c.targetClient.Update(ctx, existingRole); err != nil {
 			return nil, fmt.Errorf("updating Role: %w", err)
 		}
+		c.recordUpdated(claim, existingRole)
 	}
 
+	recordManagedObject(claim, managedObjectRef(desiredRole))
 	return desiredRole, nil
 }
 
@@ -265,7 +554,7 @@ func (c *PermissionClaimController) reconcileClusterRole(
 ) (*rbacv1.ClusterRole, error) {
 	desiredRole := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: claim.Name,
+			Name: clusterScopedName(claim),
 		},
 		Rules: claim.Spec.ClusterRules,
 	}
@@ -282,69 +571,108 @@ func (c *PermissionClaimController) reconcileClusterRole(
 		if err := c.targetClient.Create(ctx, desiredRole); err != nil {
 			return nil, err
 		}
+		recordManagedObject(claim, managedObjectRef(desiredRole))
+		c.recordCreated(claim, desiredRole)
 		return desiredRole, nil
 	}
 
-	// existing Role
+	// existing ClusterRole
+	if !c.ownerStrategy.IsOwner(claim, existingRole) {
+		return nil, newNotOwnedError("ClusterRole", existingRole)
+	}
 	if !equality.Semantic.DeepEqual(desiredRole.Rules, existingRole.Rules) {
 		existingRole.Rules = desiredRole.Rules
 		if err := c.targetClient.Update(ctx, existingRole); err != nil {
 			return nil, fmt.Errorf("updating Role: %w", err)
 		}
+		c.recordUpdated(claim, existingRole)
 	}
 
+	recordManagedObject(claim, managedObjectRef(desiredRole))
 	return desiredRole, nil
 }
 
+// reconcileRoleBinding binds the ServiceAccount to the given Role or ClusterRole in a namespace.
 func (c *PermissionClaimController) reconcileRoleBinding(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
-	role *rbacv1.Role, sa *corev1.ServiceAccount,
+	name, namespace string, role client.Object, sa *corev1.ServiceAccount,
 ) error {
-	roleGVK, _ := apiutil.GVKForObject(role.DeepCopy(), c.scheme)
+	roleGVK, _ := apiutil.GVKForObject(role.DeepCopyObject(), c.scheme)
 	saGVK, _ := apiutil.GVKForObject(sa.DeepCopyObject(), c.scheme)
-	desiredRole := &rbacv1.RoleBinding{
+	desiredBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      claim.Name,
-			Namespace: claim.Spec.Namespace,
+			Name:      name,
+			Namespace: namespace,
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: roleGVK.Group,
 			Kind:     roleGVK.Kind,
-			Name:     role.Name,
+			Name:     role.GetName(),
 		},
 		Subjects: []rbacv1.Subject{
 			{
-				APIGroup: saGVK.Group,
-				Kind:     saGVK.Kind,
-				Name:     sa.Name,
+				APIGroup:  saGVK.Group,
+				Kind:      saGVK.Kind,
+				Name:      sa.Name,
+				Namespace: sa.Namespace,
 			},
 		},
 	}
-	if err := c.ownerStrategy.SetControllerReference(claim, desiredRole, c.scheme); err != nil {
+	if err := c.ownerStrategy.SetControllerReference(claim, desiredBinding, c.scheme); err != nil {
 		return fmt.Errorf("set controller reference: %w", err)
 	}
 
-	if err := c.targetClient.Create(ctx, desiredRole); err != nil {
-		if errors.IsAlreadyExists(err) {
-			// ok
-			return nil
+	existingBinding := &rbacv1.RoleBinding{}
+	err := c.targetClient.Get(ctx, client.ObjectKeyFromObject(desiredBinding), existingBinding)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting RoleBinding: %w", err)
+	}
+	if errors.IsNotFound(err) {
+		if err := c.targetClient.Create(ctx, desiredBinding); err != nil {
+			return err
+		}
+		recordManagedObject(claim, managedObjectRef(desiredBinding))
+		c.recordCreated(claim, desiredBinding)
+		return nil
+	}
+
+	// existing RoleBinding
+	if !c.ownerStrategy.IsOwner(claim, existingBinding) {
+		return newNotOwnedError("RoleBinding", existingBinding)
+	}
+	recordManagedObject(claim, managedObjectRef(desiredBinding))
+	if !equality.Semantic.DeepEqual(desiredBinding.RoleRef, existingBinding.RoleRef) {
+		// roleRef is immutable, so the binding has to be recreated.
+		if err := c.targetClient.Delete(ctx, existingBinding); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting RoleBinding: %w", err)
 		}
-		return err
+		if err := c.targetClient.Create(ctx, desiredBinding); err != nil {
+			return fmt.Errorf("recreating RoleBinding: %w", err)
+		}
+		c.recordUpdated(claim, desiredBinding)
+		return nil
+	}
+	if !equality.Semantic.DeepEqual(desiredBinding.Subjects, existingBinding.Subjects) {
+		existingBinding.Subjects = desiredBinding.Subjects
+		if err := c.targetClient.Update(ctx, existingBinding); err != nil {
+			return fmt.Errorf("updating RoleBinding: %w", err)
+		}
+		c.recordUpdated(claim, existingBinding)
 	}
 
 	return nil
 }
 
+// reconcileClusterRoleBinding binds the ServiceAccount to the given ClusterRole cluster-wide.
 func (c *PermissionClaimController) reconcileClusterRoleBinding(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
-	role *rbacv1.ClusterRole, sa *corev1.ServiceAccount,
+	name string, role *rbacv1.ClusterRole, sa *corev1.ServiceAccount,
 ) error {
 	roleGVK, _ := apiutil.GVKForObject(role.DeepCopy(), c.scheme)
 	saGVK, _ := apiutil.GVKForObject(sa.DeepCopyObject(), c.scheme)
-	desiredRole := &rbacv1.ClusterRoleBinding{
+	desiredBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      claim.Name,
-			Namespace: claim.Spec.Namespace,
+			Name: name,
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: roleGVK.Group,
@@ -360,16 +688,46 @@ func (c *PermissionClaimController) reconcileClusterRoleBinding(
 			},
 		},
 	}
-	if err := c.ownerStrategy.SetControllerReference(claim, desiredRole, c.scheme); err != nil {
+	if err := c.ownerStrategy.SetControllerReference(claim, desiredBinding, c.scheme); err != nil {
 		return fmt.Errorf("set controller reference: %w", err)
 	}
 
-	if err := c.targetClient.Create(ctx, desiredRole); err != nil {
-		if errors.IsAlreadyExists(err) {
-			// ok
-			return nil
+	existingBinding := &rbacv1.ClusterRoleBinding{}
+	err := c.targetClient.Get(ctx, client.ObjectKeyFromObject(desiredBinding), existingBinding)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting ClusterRoleBinding: %w", err)
+	}
+	if errors.IsNotFound(err) {
+		if err := c.targetClient.Create(ctx, desiredBinding); err != nil {
+			return err
 		}
-		return err
+		recordManagedObject(claim, managedObjectRef(desiredBinding))
+		c.recordCreated(claim, desiredBinding)
+		return nil
+	}
+
+	// existing ClusterRoleBinding
+	if !c.ownerStrategy.IsOwner(claim, existingBinding) {
+		return newNotOwnedError("ClusterRoleBinding", existingBinding)
+	}
+	recordManagedObject(claim, managedObjectRef(desiredBinding))
+	if !equality.Semantic.DeepEqual(desiredBinding.RoleRef, existingBinding.RoleRef) {
+		// roleRef is immutable, so the binding has to be recreated.
+		if err := c.targetClient.Delete(ctx, existingBinding); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting ClusterRoleBinding: %w", err)
+		}
+		if err := c.targetClient.Create(ctx, desiredBinding); err != nil {
+			return fmt.Errorf("recreating ClusterRoleBinding: %w", err)
+		}
+		c.recordUpdated(claim, desiredBinding)
+		return nil
+	}
+	if !equality.Semantic.DeepEqual(desiredBinding.Subjects, existingBinding.Subjects) {
+		existingBinding.Subjects = desiredBinding.Subjects
+		if err := c.targetClient.Update(ctx, existingBinding); err != nil {
+			return fmt.Errorf("updating ClusterRoleBinding: %w", err)
+		}
+		c.recordUpdated(claim, existingBinding)
 	}
 
 	return nil
@@ -377,36 +735,65 @@ func (c *PermissionClaimController) reconcileClusterRoleBinding(
 
 func (c *PermissionClaimController) SetupWithManager(mgr ctrl.Manager) error {
 	t := &permissionsv1alpha1.PermissionClaim{}
-	h := c.ownerStrategy.EnqueueRequestForOwner(t, true)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	claimCtrl, err := ctrl.NewControllerManagedBy(mgr).
 		For(t).
 		Owns(&corev1.Secret{}).
 		Watches(
-			source.NewKindWithCache(&corev1.ServiceAccount{}, c.targetCache),
-			h,
+			&source.Kind{Type: &permissionsv1alpha1.PermissionClaimApproval{}},
+			handler.EnqueueRequestsFromMapFunc(enqueueApprovedClaim),
 		).
 		Watches(
-			source.NewKindWithCache(&rbacv1.ClusterRole{}, c.targetCache),
-			h,
+			&source.Kind{Type: &permissionsv1alpha1.PermissionPolicy{}},
+			handler.EnqueueRequestsFromMapFunc(c.enqueueClaimsForPolicy),
 		).
 		Watches(
-			source.NewKindWithCache(&rbacv1.Role{}, c.targetCache),
-			h,
+			&source.Kind{Type: &permissionsv1alpha1.TargetCluster{}},
+			handler.EnqueueRequestsFromMapFunc(c.enqueueClaimsForTargetCluster),
 		).
-		Watches(
-			source.NewKindWithCache(&rbacv1.ClusterRoleBinding{}, c.targetCache),
-			h,
-		).
-		Watches(
-			source.NewKindWithCache(&rbacv1.RoleBinding{}, c.targetCache),
-			h,
-		).
-		Watches(
-			source.NewKindWithCache(&corev1.Secret{}, c.targetCache),
-			h,
-		).
-		Complete(c)
+		Build(c)
+	if err != nil {
+		return err
+	}
+
+	// Watches on target clusters are added as clusters join the pool.
+	if cluster, err := c.targetClusters.Get(""); err == nil {
+		if err := c.watchTargetCluster(claimCtrl, cluster); err != nil {
+			return err
+		}
+	}
+	c.targetClusters.OnClusterAdded(func(cluster *targetclusters.Cluster) error {
+		return c.watchTargetCluster(claimCtrl, cluster)
+	})
+	return nil
+}
+
+// watchTargetCluster watches objects on the given target cluster.
+// Called once per cluster, the watches follow the cluster when it is refreshed.
+func (c *PermissionClaimController) watchTargetCluster(
+	claimCtrl controller.Controller, cluster *targetclusters.Cluster,
+) error {
+	h := c.ownerStrategy.EnqueueRequestForOwner(&permissionsv1alpha1.PermissionClaim{}, true)
+	for _, w := range []struct {
+		obj     client.Object
+		handler handler.EventHandler
+	}{
+		{&corev1.ServiceAccount{}, h},
+		{&rbacv1.ClusterRole{}, h},
+		{&rbacv1.Role{}, h},
+		{&rbacv1.ClusterRoleBinding{}, h},
+		{&rbacv1.RoleBinding{}, h},
+		{&corev1.Secret{}, h},
+		{&rbacv1.ClusterRole{}, handler.EnqueueRequestsFromMapFunc(
+			c.enqueueClaimsReferencingClusterRole(cluster.Name))},
+		{&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(
+			c.enqueueClaimsWithNamespaceSelector(cluster.Name))},
+	} {
+		if err := claimCtrl.Watch(cluster.Kind(w.obj), w.handler); err != nil {
+			return fmt.Errorf("watching %T on target cluster: %w", w.obj, err)
+		}
+	}
+	return nil
 }
 
 const cleanupFinalizer = "permissions.thetechnick.ninja/cleanup"
@@ -414,21 +801,37 @@ const cleanupFinalizer = "permissions.thetechnick.ninja/cleanup"
 func (c *PermissionClaimController) handleDeletion(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
 ) error {
-	// Delete stuff
-	objs := []client.Object{
-		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: claim.Name}},
-		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Namespace}},
-		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: claim.Name}},
-		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Namespace}},
-		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: claim.Name, Namespace: claim.Namespace}},
-	}
-	for _, obj := range objs {
-		if err := c.targetClient.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
-			return fmt.Errorf("cleanup on target cluster: %w", err)
+	remaining, err := c.deleteManagedObjects(ctx, claim)
+	if err != nil {
+		return err
+	}
+
+	if len(remaining) > 0 {
+		claim.Status.ManagedObjects = remaining
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimTerminating,
+			Status:             metav1.ConditionTrue,
+			Reason:             "CleaningUp",
+			Message:            fmt.Sprintf("Waiting for %d objects on the target cluster to be deleted.", len(remaining)),
+			ObservedGeneration: claim.Generation,
+		})
+		claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseTerminating
+		claim.Status.ObservedGeneration = claim.Generation
+		if err := c.client.Status().Update(ctx, claim); err != nil {
+			return fmt.Errorf("updating status: %w", err)
 		}
+		return nil
 	}
 
+	return c.removeCleanupFinalizer(ctx, claim)
+}
+
+func (c *PermissionClaimController) removeCleanupFinalizer(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
 	if controllerutil.ContainsFinalizer(claim, cleanupFinalizer) {
+		c.recorder.Event(claim, corev1.EventTypeNormal, eventReasonCleanupCompleted,
+			"All objects on the target cluster have been deleted")
 		controllerutil.RemoveFinalizer(claim, cleanupFinalizer)
 
 		if err := c.client.Update(ctx, claim); err != nil {
@@ -438,6 +841,54 @@ func (c *PermissionClaimController) handleDeletion(
 	return nil
 }
 
+// deleteManagedObjects deletes objects on the target cluster owned by the claim.
+// Only deletes objects of the given kinds, or all objects if no kind is given.
+// Returns the objects that still exist.
+func (c *PermissionClaimController) deleteManagedObjects(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	kinds ...string,
+) ([]permissionsv1alpha1.ManagedObjectReference, error) {
+	log := c.log.WithValues("PermissionClaim", client.ObjectKeyFromObject(claim).String())
+
+	// bindings go first, to revoke access as early as possible.
+	refs := expectedManagedObjects(claim)
+	for _, ref := range append(claim.Status.ManagedObjects, legacyManagedObjects(claim)...) {
+		refs = appendManagedObjectRef(refs, ref)
+	}
+
+	var remaining []permissionsv1alpha1.ManagedObjectReference
+	for _, ref := range refs {
+		if len(kinds) > 0 && !slices.Contains(kinds, ref.Kind) {
+			continue
+		}
+		obj, err := objectForManagedObjectRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		err = c.targetClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting %s: %w", ref.Kind, err)
+		}
+		if !c.ownerStrategy.IsOwner(claim, obj) {
+			log.Info("skipping cleanup of object not owned by claim",
+				"kind", ref.Kind, "object", client.ObjectKeyFromObject(obj).String())
+			continue
+		}
+
+		remaining = append(remaining, ref)
+		if !obj.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err := c.targetClient.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("cleanup on target cluster: %w", err)
+		}
+	}
+	return remaining, nil
+}
+
 // ensures the cache finalizer is set on the given object
 func (c *PermissionClaimController) ensureCacheFinalizer(
 	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
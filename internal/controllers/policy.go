@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/rbacrules"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// evaluatePolicies checks the claim against all PermissionPolicies that apply to it
+// and updates the Denied condition accordingly.
+// Returns false if the claim requests anything not allowed by these policies.
+func (c *PermissionClaimController) evaluatePolicies(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (bool, error) {
+	policyList := &permissionsv1alpha1.PermissionPolicyList{}
+	if err := c.client.List(ctx, policyList); err != nil {
+		return false, fmt.Errorf("listing PermissionPolicies: %w", err)
+	}
+
+	var (
+		policyNames             []string
+		allowedRules            []rbacv1.PolicyRule
+		allowedClusterRules     []rbacv1.PolicyRule
+		allowedTargetNamespaces []string
+		allowedClusterRoleRefs  []string
+		allowedTargetClusters   []string
+	)
+	for _, policy := range policyList.Items {
+		if !policyAppliesTo(&policy, claim) {
+			continue
+		}
+		policyNames = append(policyNames, policy.Name)
+		allowedRules = append(allowedRules, policy.Spec.AllowedRules...)
+		allowedClusterRules = append(allowedClusterRules, policy.Spec.AllowedClusterRules...)
+		allowedTargetNamespaces = append(allowedTargetNamespaces, policy.Spec.AllowedTargetNamespaces...)
+		allowedClusterRoleRefs = append(allowedClusterRoleRefs, policy.Spec.AllowedClusterRoleRefs...)
+		allowedTargetClusters = append(allowedTargetClusters, policy.Spec.AllowedTargetClusters...)
+	}
+	if len(policyNames) == 0 {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimDenied)
+		return true, nil
+	}
+
+	var violations []string
+	if name := targetClusterName(claim); len(name) > 0 &&
+		!slices.Contains(allowedTargetClusters, "*") &&
+		!slices.Contains(allowedTargetClusters, name) {
+		violations = append(violations, "TargetCluster not allowed: "+name)
+	}
+	var namespacedRules []rbacv1.PolicyRule
+	var deniedNamespaces []string
+	for _, nsRules := range desiredNamespaceRules(claim) {
+		namespacedRules = append(namespacedRules, nsRules.rules...)
+		if !slices.Contains(allowedTargetNamespaces, "*") &&
+			!slices.Contains(allowedTargetNamespaces, nsRules.namespace) {
+			deniedNamespaces = append(deniedNamespaces, nsRules.namespace)
+		}
+	}
+	if len(deniedNamespaces) > 0 {
+		violations = append(violations,
+			"namespaces not allowed: "+strings.Join(deniedNamespaces, ", "))
+	}
+	if ok, uncovered := rbacrules.Covers(allowedRules, namespacedRules); !ok {
+		violations = append(violations,
+			"rules not allowed: "+rbacrules.Strings(uncovered))
+	}
+	if ok, uncovered := rbacrules.Covers(allowedClusterRules, claim.Spec.ClusterRules); !ok {
+		violations = append(violations,
+			"clusterRules not allowed: "+rbacrules.Strings(uncovered))
+	}
+	var deniedRefs []string
+	for _, ref := range append(append([]string{}, claim.Spec.RoleRefs...), claim.Spec.ClusterRoleRefs...) {
+		if !slices.Contains(allowedClusterRoleRefs, "*") &&
+			!slices.Contains(allowedClusterRoleRefs, ref) &&
+			!slices.Contains(deniedRefs, ref) {
+			deniedRefs = append(deniedRefs, ref)
+		}
+	}
+	if len(deniedRefs) > 0 {
+		violations = append(violations,
+			"ClusterRole references not allowed: "+strings.Join(deniedRefs, ", "))
+	}
+
+	if len(violations) > 0 {
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimDenied,
+			Status:             metav1.ConditionTrue,
+			Reason:             "PolicyViolation",
+			Message:            strings.Join(violations, "; "),
+			ObservedGeneration: claim.Generation,
+		})
+		claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseDenied
+		return false, nil
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:   permissionsv1alpha1.PermissionClaimDenied,
+		Status: metav1.ConditionFalse,
+		Reason: "Allowed",
+		Message: fmt.Sprintf("Allowed by PermissionPolicies: %s.",
+			strings.Join(policyNames, ", ")),
+		ObservedGeneration: claim.Generation,
+	})
+	return true, nil
+}
+
+func policyAppliesTo(
+	policy *permissionsv1alpha1.PermissionPolicy, claim *permissionsv1alpha1.PermissionClaim,
+) bool {
+	return len(policy.Spec.ClaimNamespaces) == 0 ||
+		slices.Contains(policy.Spec.ClaimNamespaces, claim.Namespace)
+}
+
+// enqueueClaimsForPolicy maps PermissionPolicy events
+// to all PermissionClaims the policy applies to.
+func (c *PermissionClaimController) enqueueClaimsForPolicy(obj client.Object) []reconcile.Request {
+	policy, ok := obj.(*permissionsv1alpha1.PermissionPolicy)
+	if !ok {
+		return nil
+	}
+
+	claimList := &permissionsv1alpha1.PermissionClaimList{}
+	if err := c.client.List(context.Background(), claimList); err != nil {
+		c.log.Error(err, "listing PermissionClaims for PermissionPolicy event", "PermissionPolicy", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, claim := range claimList.Items {
+		if !policyAppliesTo(policy, &claim) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&claim),
+		})
+	}
+	return requests
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/rbacrules"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Number of missing permissions listed in the RequesterAuthorized condition.
+const maxReportedMissingPermissions = 10
+
+// checkRequesterPermissions verifies via SubjectAccessReviews on the target cluster,
+// that the user who last changed the claim could grant the requested permissions themselves
+// and updates the RequesterAuthorized condition accordingly.
+// Returns false if the claim exceeds the permissions of the requester.
+func (c *PermissionClaimController) checkRequesterPermissions(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (bool, error) {
+	if !c.checkRequester {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimRequesterAuthorized)
+		claim.Status.RequesterCheckHash = ""
+		return true, nil
+	}
+
+	requester := &authenticationv1.UserInfo{}
+	annotation := claim.Annotations[permissionsv1alpha1.RequesterAnnotation]
+	if len(annotation) == 0 {
+		c.setRequesterUnauthorized(claim, "RequesterUnknown",
+			fmt.Sprintf("Missing %s annotation, is the mutating webhook installed?",
+				permissionsv1alpha1.RequesterAnnotation))
+		claim.Status.RequesterCheckHash = ""
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(annotation), requester); err != nil {
+		c.setRequesterUnauthorized(claim, "RequesterUnknown",
+			fmt.Sprintf("Invalid %s annotation: %v", permissionsv1alpha1.RequesterAnnotation, err))
+		claim.Status.RequesterCheckHash = ""
+		return false, nil
+	}
+
+	// SubjectAccessReviews are expensive, reuse the last result as long as its inputs are unchanged.
+	checkHash, err := c.requesterCheckHash(ctx, claim)
+	if err != nil {
+		return false, err
+	}
+	if cond := meta.FindStatusCondition(
+		claim.Status.Conditions, permissionsv1alpha1.PermissionClaimRequesterAuthorized,
+	); cond != nil && claim.Status.RequesterCheckHash == checkHash {
+		if cond.Status != metav1.ConditionTrue {
+			c.setRequesterUnauthorized(claim, cond.Reason, cond.Message)
+			return false, nil
+		}
+		return true, nil
+	}
+
+	var missing []string
+	for _, nsRules := range desiredNamespaceRules(claim) {
+		m, err := c.missingPermissions(ctx, requester, nsRules.namespace, nsRules.rules)
+		if err != nil {
+			return false, err
+		}
+		missing = append(missing, m...)
+	}
+	m, err := c.missingPermissions(ctx, requester, "", claim.Spec.ClusterRules)
+	if err != nil {
+		return false, err
+	}
+	missing = append(missing, m...)
+
+	for _, name := range claim.Spec.RoleRefs {
+		for _, ns := range targetNamespaces(claim) {
+			m, err := c.missingBindPermissions(ctx, requester, ns, name)
+			if err != nil {
+				return false, err
+			}
+			missing = append(missing, m...)
+		}
+	}
+	for _, name := range claim.Spec.ClusterRoleRefs {
+		m, err := c.missingBindPermissions(ctx, requester, "", name)
+		if err != nil {
+			return false, err
+		}
+		missing = append(missing, m...)
+	}
+
+	if len(missing) > 0 {
+		message := fmt.Sprintf("%s is missing permissions: ", requester.Username)
+		if len(missing) > maxReportedMissingPermissions {
+			message += strings.Join(missing[:maxReportedMissingPermissions], "; ") +
+				fmt.Sprintf("; and %d more", len(missing)-maxReportedMissingPermissions)
+		} else {
+			message += strings.Join(missing, "; ")
+		}
+		c.setRequesterUnauthorized(claim, "MissingPermissions", message)
+		claim.Status.RequesterCheckHash = checkHash
+		return false, nil
+	}
+
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimRequesterAuthorized,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RequesterHoldsPermissions",
+		Message:            fmt.Sprintf("%s holds all requested permissions.", requester.Username),
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.RequesterCheckHash = checkHash
+	return true, nil
+}
+
+// requesterCheckHash returns a hash over everything the permissions of the requester are checked against:
+// the spec and requester of the claim, the namespaces matched by its selector
+// and the referenced ClusterRoles, as their rules are checked when binding them is not allowed.
+func (c *PermissionClaimController) requesterCheckHash(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (string, error) {
+	hash, err := specHash(claim)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintln(h, hash)
+	fmt.Fprintln(h, claim.Annotations[permissionsv1alpha1.RequesterAnnotation])
+	fmt.Fprintln(h, strings.Join(claim.Status.MatchedNamespaces, ","))
+	for _, name := range append(append([]string{}, claim.Spec.RoleRefs...), claim.Spec.ClusterRoleRefs...) {
+		clusterRole := &rbacv1.ClusterRole{}
+		err := c.targetClient.Get(ctx, client.ObjectKey{Name: name}, clusterRole)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("getting ClusterRole %s: %w", name, err)
+		}
+		// empty for ClusterRoles that do not exist (yet).
+		fmt.Fprintln(h, name, clusterRole.ResourceVersion)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *PermissionClaimController) setRequesterUnauthorized(
+	claim *permissionsv1alpha1.PermissionClaim, reason, message string,
+) {
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimRequesterAuthorized,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseDenied
+}
+
+// Returns the parts of rules the requester is not allowed to perform.
+// An empty namespace checks cluster-wide access.
+func (c *PermissionClaimController) missingPermissions(
+	ctx context.Context, requester *authenticationv1.UserInfo,
+	namespace string, rules []rbacv1.PolicyRule,
+) ([]string, error) {
+	var missing []string
+	for _, rule := range rules {
+		for _, atomic := range rbacrules.Breakdown(rule) {
+			allowed, err := c.subjectAccessReview(ctx, requester, namespace, atomic)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				continue
+			}
+			if len(namespace) > 0 {
+				missing = append(missing,
+					fmt.Sprintf("%s in namespace %s", rbacrules.String(atomic), namespace))
+			} else {
+				missing = append(missing, rbacrules.String(atomic))
+			}
+		}
+	}
+	return missing, nil
+}
+
+// Returns why the requester is not allowed to bind the given ClusterRole.
+// Like the RBAC API, binding is allowed when the requester
+// holds the "bind" verb for the ClusterRole or all of its permissions.
+func (c *PermissionClaimController) missingBindPermissions(
+	ctx context.Context, requester *authenticationv1.UserInfo,
+	namespace, clusterRoleName string,
+) ([]string, error) {
+	allowed, err := c.subjectAccessReview(ctx, requester, namespace, rbacv1.PolicyRule{
+		Verbs:         []string{"bind"},
+		APIGroups:     []string{rbacv1.GroupName},
+		Resources:     []string{"clusterroles"},
+		ResourceNames: []string{clusterRoleName},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if allowed {
+		return nil, nil
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	err = c.targetClient.Get(ctx, client.ObjectKey{Name: clusterRoleName}, clusterRole)
+	if errors.IsNotFound(err) {
+		// Reported via the RoleRefsResolved condition.
+		// Checked again when the ClusterRole is created.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting ClusterRole %s: %w", clusterRoleName, err)
+	}
+	return c.missingPermissions(ctx, requester, namespace, clusterRole.Rules)
+}
+
+// Checks whether the requester may perform the action described by the given atomic rule.
+func (c *PermissionClaimController) subjectAccessReview(
+	ctx context.Context, requester *authenticationv1.UserInfo,
+	namespace string, atomic rbacv1.PolicyRule,
+) (bool, error) {
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   requester.Username,
+			UID:    requester.UID,
+			Groups: requester.Groups,
+		},
+	}
+	if len(requester.Extra) > 0 {
+		sar.Spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for k, v := range requester.Extra {
+			sar.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+
+	if len(atomic.NonResourceURLs) > 0 {
+		sar.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: atomic.NonResourceURLs[0],
+			Verb: atomic.Verbs[0],
+		}
+	} else {
+		resource, subresource := atomic.Resources[0], ""
+		if i := strings.Index(resource, "/"); i != -1 {
+			resource, subresource = resource[:i], resource[i+1:]
+		}
+		sar.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace:   namespace,
+			Verb:        atomic.Verbs[0],
+			Group:       atomic.APIGroups[0],
+			Resource:    resource,
+			Subresource: subresource,
+		}
+		if len(atomic.ResourceNames) > 0 {
+			sar.Spec.ResourceAttributes.Name = atomic.ResourceNames[0]
+		}
+	}
+
+	if err := c.targetClient.Create(ctx, sar); err != nil {
+		return false, fmt.Errorf("creating SubjectAccessReview: %w", err)
+	}
+	return sar.Status.Allowed, nil
+}
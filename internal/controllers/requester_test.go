@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// answers SubjectAccessReviews and counts them.
+type sarClient struct {
+	client.Client
+	allowed bool
+	reviews int
+}
+
+func (c *sarClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	sar, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	c.reviews++
+	sar.Status.Allowed = c.allowed
+	return nil
+}
+
+func TestCheckRequesterPermissions_Cached(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	newClaim := func() *permissionsv1alpha1.PermissionClaim {
+		return &permissionsv1alpha1.PermissionClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "default",
+				Annotations: map[string]string{
+					permissionsv1alpha1.RequesterAnnotation: `{"username":"alice"}`,
+				},
+			},
+			Spec: permissionsv1alpha1.PermissionClaimSpec{
+				Namespace: "ns",
+				ClusterRules: []rbacv1.PolicyRule{{
+					APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get"},
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		allowed     bool
+		change      func(claim *permissionsv1alpha1.PermissionClaim)
+		wantReviews int
+	}{
+		{
+			name:    "unchanged allowed",
+			allowed: true,
+		},
+		{
+			name: "unchanged denied",
+		},
+		{
+			name:    "spec changed",
+			allowed: true,
+			change: func(claim *permissionsv1alpha1.PermissionClaim) {
+				claim.Spec.ClusterRules[0].Verbs = []string{"get", "list"}
+			},
+			wantReviews: 2,
+		},
+		{
+			name:    "requester changed",
+			allowed: true,
+			change: func(claim *permissionsv1alpha1.PermissionClaim) {
+				claim.Annotations[permissionsv1alpha1.RequesterAnnotation] = `{"username":"bob"}`
+			},
+			wantReviews: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targetClient := &sarClient{
+				Client:  fake.NewClientBuilder().WithScheme(scheme).Build(),
+				allowed: test.allowed,
+			}
+			c := &PermissionClaimController{
+				log:            logr.Discard(),
+				scheme:         scheme,
+				targetClient:   targetClient,
+				checkRequester: true,
+			}
+
+			claim := newClaim()
+			if _, err := c.checkRequesterPermissions(context.Background(), claim); err != nil {
+				t.Fatal(err)
+			}
+			targetClient.reviews = 0
+
+			if test.change != nil {
+				test.change(claim)
+			}
+			authorized, err := c.checkRequesterPermissions(context.Background(), claim)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authorized != test.allowed {
+				t.Errorf("authorized = %v, want %v", authorized, test.allowed)
+			}
+			if targetClient.reviews != test.wantReviews {
+				t.Errorf("SubjectAccessReviews = %d, want %d", targetClient.reviews, test.wantReviews)
+			}
+		})
+	}
+}
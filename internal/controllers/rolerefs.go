@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileRoleRefs binds the ServiceAccount to existing ClusterRoles
+// referenced in .spec.roleRefs and .spec.clusterRoleRefs.
+// Bindings for missing ClusterRoles are not created and reported via the RoleRefsResolved condition.
+func (c *PermissionClaimController) reconcileRoleRefs(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	sa *corev1.ServiceAccount,
+) error {
+	if len(claim.Spec.RoleRefs) == 0 && len(claim.Spec.ClusterRoleRefs) == 0 {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, permissionsv1alpha1.PermissionClaimRoleRefsResolved)
+		return nil
+	}
+
+	var missing []string
+	clusterRoles := map[string]*rbacv1.ClusterRole{}
+	for _, name := range append(append([]string{}, claim.Spec.RoleRefs...), claim.Spec.ClusterRoleRefs...) {
+		if _, ok := clusterRoles[name]; ok {
+			continue
+		}
+		clusterRole := &rbacv1.ClusterRole{}
+		err := c.targetClient.Get(ctx, client.ObjectKey{Name: name}, clusterRole)
+		if errors.IsNotFound(err) {
+			missing = append(missing, name)
+			clusterRoles[name] = nil
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("getting ClusterRole %s: %w", name, err)
+		}
+		clusterRoles[name] = clusterRole
+	}
+
+	for _, name := range claim.Spec.ClusterRoleRefs {
+		clusterRole := clusterRoles[name]
+		if clusterRole == nil {
+			continue
+		}
+		if err := c.reconcileClusterRoleBinding(
+			ctx, claim, clusterRoleRefBindingName(claim, name), clusterRole, sa); err != nil {
+			return fmt.Errorf("reconciling ClusterRoleBinding for ClusterRole %s: %w", name, err)
+		}
+	}
+
+	for _, name := range claim.Spec.RoleRefs {
+		clusterRole := clusterRoles[name]
+		if clusterRole == nil {
+			continue
+		}
+		for _, ns := range targetNamespaces(claim) {
+			if err := c.reconcileRoleBinding(
+				ctx, claim, roleRefBindingName(claim, name), ns, clusterRole, sa); err != nil {
+				return fmt.Errorf("reconciling RoleBinding for ClusterRole %s in namespace %s: %w", name, ns, err)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimRoleRefsResolved,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ClusterRoleNotFound",
+			Message:            "Referenced ClusterRoles not found: " + strings.Join(missing, ", "),
+			ObservedGeneration: claim.Generation,
+		})
+		return nil
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimRoleRefsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		ObservedGeneration: claim.Generation,
+	})
+	return nil
+}
+
+// enqueueClaimsReferencingClusterRole maps ClusterRole events
+// on the given target cluster to all PermissionClaims on it referencing the ClusterRole.
+func (c *PermissionClaimController) enqueueClaimsReferencingClusterRole(cluster string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		claimList := &permissionsv1alpha1.PermissionClaimList{}
+		if err := c.client.List(context.Background(), claimList); err != nil {
+			c.log.Error(err, "listing PermissionClaims for ClusterRole event", "ClusterRole", obj.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, claim := range claimList.Items {
+			if targetClusterName(&claim) != cluster {
+				continue
+			}
+			if !slices.Contains(claim.Spec.RoleRefs, obj.GetName()) &&
+				!slices.Contains(claim.Spec.ClusterRoleRefs, obj.GetName()) {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&claim),
+			})
+		}
+		return requests
+	}
+}
+
+// returns the name of the RoleBinding for a ClusterRole referenced via .spec.roleRefs.
+func roleRefBindingName(claim *permissionsv1alpha1.PermissionClaim, clusterRole string) string {
+	return truncateName(claim.Name+":"+clusterRole, validation.DNS1123SubdomainMaxLength)
+}
+
+// returns the name of the ClusterRoleBinding for a ClusterRole referenced via .spec.clusterRoleRefs.
+func clusterRoleRefBindingName(claim *permissionsv1alpha1.PermissionClaim, clusterRole string) string {
+	return truncateName(
+		clusterScopedNamePrefix+":"+claim.Namespace+":"+claim.Name+":"+clusterRole,
+		validation.DNS1123SubdomainMaxLength)
+}
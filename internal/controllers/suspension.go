@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// handleSuspension removes all bindings of a suspended claim from the target cluster,
+// to cut off access while keeping the ServiceAccount and Roles in place.
+// Credentials are also revoked, if requested in the spec.
+func (c *PermissionClaimController) handleSuspension(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) error {
+	kinds := []string{"RoleBinding", "ClusterRoleBinding"}
+	if claim.Spec.RevokeCredentialsWhenSuspended {
+		// Only legacy token Secrets can be revoked,
+		// TokenRequest tokens are only invalidated with the ServiceAccount.
+		kinds = append(kinds, "Secret")
+	}
+
+	remaining, err := c.deleteManagedObjects(ctx, claim, kinds...)
+	if err != nil {
+		return err
+	}
+	// Forget deleted objects, so they are not tracked until they are recreated on resume.
+	var refs []permissionsv1alpha1.ManagedObjectReference
+	for _, ref := range claim.Status.ManagedObjects {
+		if !slices.Contains(kinds, ref.Kind) || containsManagedObjectRef(remaining, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	claim.Status.ManagedObjects = refs
+
+	message := "Bindings have been removed from the target cluster."
+	if claim.Spec.RevokeCredentialsWhenSuspended {
+		if err := c.deleteKubeconfigSecret(ctx, claim); err != nil {
+			return err
+		}
+		claim.Status.Credentials = nil
+		message = "Bindings and credentials have been removed from the target cluster."
+	}
+
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimSuspended,
+		Status:             metav1.ConditionTrue,
+		Reason:             "SuspendedBySpec",
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimBound,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Suspended",
+		Message:            "Claim is suspended.",
+		ObservedGeneration: claim.Generation,
+	})
+	if claim.Status.Phase != permissionsv1alpha1.PermissionClaimPhaseSuspended {
+		c.recorder.Event(claim, corev1.EventTypeNormal, eventReasonSuspended, message)
+	}
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhaseSuspended
+	return nil
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/targetclusters"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Interval in which the health of reachable TargetClusters is checked.
+const healthCheckInterval = time.Minute
+
+// TargetClusterController adds TargetClusters to the pool of target clusters,
+// refreshes them when their kubeconfig changes and removes them once deleted.
+type TargetClusterController struct {
+	log    logr.Logger
+	client client.Client
+
+	targetClusters *targetclusters.Pool
+	// Namespace kubeconfig Secrets have to be located in.
+	// Empty allows all namespaces.
+	secretNamespace string
+}
+
+func NewTargetClusterController(
+	log logr.Logger,
+	client client.Client,
+	targetClusters *targetclusters.Pool,
+	secretNamespace string,
+) *TargetClusterController {
+	return &TargetClusterController{
+		log:    log,
+		client: client,
+
+		targetClusters:  targetClusters,
+		secretNamespace: secretNamespace,
+	}
+}
+
+func (c *TargetClusterController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	targetCluster := &permissionsv1alpha1.TargetCluster{}
+	if err := c.client.Get(ctx, req.NamespacedName, targetCluster); errors.IsNotFound(err) {
+		c.targetClusters.Remove(req.Name)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !targetCluster.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, c.handleDeletion(ctx, targetCluster)
+	}
+
+	if !controllerutil.ContainsFinalizer(targetCluster, cleanupFinalizer) {
+		controllerutil.AddFinalizer(targetCluster, cleanupFinalizer)
+		if err := c.client.Update(ctx, targetCluster); err != nil {
+			return ctrl.Result{}, fmt.Errorf("adding finalizer: %w", err)
+		}
+	}
+
+	original := targetCluster.DeepCopy()
+	var (
+		res ctrl.Result
+		err error
+	)
+	if verr := c.validateSecretRefs(targetCluster); verr != nil {
+		// Not retried, until the spec is fixed.
+		meta.SetStatusCondition(&targetCluster.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.TargetClusterAvailable,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InvalidSecretRef",
+			Message:            verr.Error(),
+			ObservedGeneration: targetCluster.Generation,
+		})
+		targetCluster.Status.Phase = permissionsv1alpha1.TargetClusterPhasePending
+	} else if cluster, cerr := c.ensureCluster(ctx, targetCluster); cerr != nil {
+		err = cerr
+		meta.SetStatusCondition(&targetCluster.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.TargetClusterAvailable,
+			Status:             metav1.ConditionFalse,
+			Reason:             "SetupFailed",
+			Message:            err.Error(),
+			ObservedGeneration: targetCluster.Generation,
+		})
+		targetCluster.Status.Phase = permissionsv1alpha1.TargetClusterPhasePending
+	} else {
+		res = c.checkHealth(ctx, targetCluster, cluster)
+	}
+	targetCluster.Status.ObservedGeneration = targetCluster.Generation
+	if perr := c.client.Status().Patch(ctx, targetCluster, client.MergeFrom(original)); perr != nil &&
+		!errors.IsNotFound(perr) {
+		if err != nil {
+			c.log.Error(perr, "patching status", "TargetCluster", req.Name)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, fmt.Errorf("patching status: %w", perr)
+	}
+	return res, err
+}
+
+// checkHealth reports the health of the target cluster in the Available condition.
+// Healthy clusters are checked again periodically, unreachable clusters with an exponential backoff.
+// PermissionClaims referencing the TargetCluster are reconciled when its status changes.
+func (c *TargetClusterController) checkHealth(
+	ctx context.Context, targetCluster *permissionsv1alpha1.TargetCluster,
+	cluster *targetclusters.Cluster,
+) ctrl.Result {
+	if err := cluster.CheckHealth(ctx); err != nil {
+		meta.SetStatusCondition(&targetCluster.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.TargetClusterAvailable,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Unreachable",
+			Message:            err.Error(),
+			ObservedGeneration: targetCluster.Generation,
+		})
+		targetCluster.Status.Phase = permissionsv1alpha1.TargetClusterPhaseUnreachable
+
+		cond := meta.FindStatusCondition(
+			targetCluster.Status.Conditions, permissionsv1alpha1.TargetClusterAvailable)
+		return ctrl.Result{RequeueAfter: targetclusters.UnreachableBackoff(cond.LastTransitionTime.Time, time.Now())}
+	}
+
+	meta.SetStatusCondition(&targetCluster.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.TargetClusterAvailable,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Connected",
+		Message:            "Target cluster API is reachable.",
+		ObservedGeneration: targetCluster.Generation,
+	})
+	targetCluster.Status.Phase = permissionsv1alpha1.TargetClusterPhaseAvailable
+	return ctrl.Result{RequeueAfter: healthCheckInterval}
+}
+
+// ensureCluster adds the TargetCluster to the pool,
+// or refreshes it, when its kubeconfig has changed.
+func (c *TargetClusterController) ensureCluster(
+	ctx context.Context, targetCluster *permissionsv1alpha1.TargetCluster,
+) (*targetclusters.Cluster, error) {
+	kubeconfig, err := c.readSecretKey(ctx, targetCluster.Spec.KubeconfigSecretRef)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	var templateKubeconfig []byte
+	if ref := targetCluster.Spec.TemplateKubeconfigSecretRef; ref != nil {
+		templateKubeconfig, err = c.readSecretKey(ctx, *ref)
+		if err != nil {
+			return nil, fmt.Errorf("reading template kubeconfig: %w", err)
+		}
+	}
+
+	cluster, err := c.targetClusters.Ensure(
+		ctx, targetCluster.Name, kubeconfig, templateKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up target cluster clients: %w", err)
+	}
+	return cluster, nil
+}
+
+// validateSecretRefs ensures all referenced Secrets are located in the namespace
+// the operator is allowed to read Secrets in.
+func (c *TargetClusterController) validateSecretRefs(
+	targetCluster *permissionsv1alpha1.TargetCluster,
+) error {
+	if len(c.secretNamespace) == 0 {
+		return nil
+	}
+	if ns := targetCluster.Spec.KubeconfigSecretRef.Namespace; ns != c.secretNamespace {
+		return fmt.Errorf(".spec.kubeconfigSecretRef.namespace must be %q, the namespace of the operator",
+			c.secretNamespace)
+	}
+	if ref := targetCluster.Spec.TemplateKubeconfigSecretRef; ref != nil && ref.Namespace != c.secretNamespace {
+		return fmt.Errorf(".spec.templateKubeconfigSecretRef.namespace must be %q, the namespace of the operator",
+			c.secretNamespace)
+	}
+	return nil
+}
+
+func (c *TargetClusterController) readSecretKey(
+	ctx context.Context, ref permissionsv1alpha1.SecretKeyReference,
+) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := c.client.Get(ctx, client.ObjectKey{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+	}, secret); err != nil {
+		return nil, fmt.Errorf("getting Secret: %w", err)
+	}
+	key := ref.Key
+	if len(key) == 0 {
+		key = corev1.ServiceAccountKubeconfigKey
+	}
+	data, ok := secret.Data[key]
+	if !ok || len(data) == 0 {
+		return nil, fmt.Errorf("key %q in Secret %s/%s is empty", key, ref.Namespace, ref.Name)
+	}
+	return data, nil
+}
+
+// handleDeletion keeps the TargetCluster in the pool,
+// until all PermissionClaims referencing it have cleaned up after themselves.
+func (c *TargetClusterController) handleDeletion(
+	ctx context.Context, targetCluster *permissionsv1alpha1.TargetCluster,
+) error {
+	claims, err := c.referencingClaims(ctx, targetCluster.Name)
+	if err != nil {
+		return err
+	}
+	if len(claims) > 0 {
+		meta.SetStatusCondition(&targetCluster.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.TargetClusterTerminating,
+			Status:             metav1.ConditionTrue,
+			Reason:             "ClaimsRemaining",
+			Message:            fmt.Sprintf("Waiting for %d PermissionClaims referencing this TargetCluster to be deleted.", len(claims)),
+			ObservedGeneration: targetCluster.Generation,
+		})
+		targetCluster.Status.Phase = permissionsv1alpha1.TargetClusterPhaseTerminating
+		targetCluster.Status.ObservedGeneration = targetCluster.Generation
+		if err := c.client.Status().Update(ctx, targetCluster); err != nil {
+			return fmt.Errorf("updating status: %w", err)
+		}
+		return nil
+	}
+
+	c.targetClusters.Remove(targetCluster.Name)
+	if controllerutil.ContainsFinalizer(targetCluster, cleanupFinalizer) {
+		controllerutil.RemoveFinalizer(targetCluster, cleanupFinalizer)
+		if err := c.client.Update(ctx, targetCluster); err != nil {
+			return fmt.Errorf("removing finalizer: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *TargetClusterController) referencingClaims(
+	ctx context.Context, name string,
+) ([]permissionsv1alpha1.PermissionClaim, error) {
+	claimList := &permissionsv1alpha1.PermissionClaimList{}
+	if err := c.client.List(ctx, claimList); err != nil {
+		return nil, fmt.Errorf("listing PermissionClaims: %w", err)
+	}
+	var claims []permissionsv1alpha1.PermissionClaim
+	for _, claim := range claimList.Items {
+		if targetClusterName(&claim) == name {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+func (c *TargetClusterController) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&permissionsv1alpha1.TargetCluster{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(c.enqueueTargetClustersForSecret),
+		).
+		Watches(
+			&source.Kind{Type: &permissionsv1alpha1.PermissionClaim{}},
+			handler.EnqueueRequestsFromMapFunc(enqueueReferencedTargetCluster),
+		).
+		Complete(c)
+}
+
+// enqueueTargetClustersForSecret maps Secret events
+// to all TargetClusters referencing the Secret.
+func (c *TargetClusterController) enqueueTargetClustersForSecret(obj client.Object) []reconcile.Request {
+	targetClusterList := &permissionsv1alpha1.TargetClusterList{}
+	if err := c.client.List(context.Background(), targetClusterList); err != nil {
+		c.log.Error(err, "listing TargetClusters for Secret event", "Secret", client.ObjectKeyFromObject(obj).String())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, targetCluster := range targetClusterList.Items {
+		refs := []permissionsv1alpha1.SecretKeyReference{targetCluster.Spec.KubeconfigSecretRef}
+		if targetCluster.Spec.TemplateKubeconfigSecretRef != nil {
+			refs = append(refs, *targetCluster.Spec.TemplateKubeconfigSecretRef)
+		}
+		for _, ref := range refs {
+			if ref.Name == obj.GetName() && ref.Namespace == obj.GetNamespace() {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&targetCluster),
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// enqueueReferencedTargetCluster maps PermissionClaim events
+// to the TargetCluster referenced by the claim,
+// so deleted TargetClusters are released after their last claim is gone.
+func enqueueReferencedTargetCluster(obj client.Object) []reconcile.Request {
+	claim, ok := obj.(*permissionsv1alpha1.PermissionClaim)
+	if !ok || claim.Spec.TargetClusterRef == nil {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: client.ObjectKey{Name: claim.Spec.TargetClusterRef.Name},
+	}}
+}
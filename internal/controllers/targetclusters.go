@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/targetclusters"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// returns the name of the TargetCluster the claim targets,
+// or an empty string for the default target cluster.
+func targetClusterName(claim *permissionsv1alpha1.PermissionClaim) string {
+	if claim.Spec.TargetClusterRef == nil {
+		return ""
+	}
+	return claim.Spec.TargetClusterRef.Name
+}
+
+// returns a copy of the controller operating on the given target cluster.
+func (c *PermissionClaimController) withTargetCluster(
+	cluster *targetclusters.Cluster,
+) *PermissionClaimController {
+	cc := *c
+	cc.baseKubeconfig = cluster.TemplateKubeconfig
+	cc.targetClient = cluster.Client
+	cc.targetServiceAccounts = cluster.ServiceAccounts
+	return &cc
+}
+
+// targetClusterUnavailable reports the given error resolving the target cluster
+// in the Bound condition and returns it.
+func targetClusterUnavailable(
+	claim *permissionsv1alpha1.PermissionClaim, err error,
+) error {
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimBound,
+		Status:             metav1.ConditionFalse,
+		Reason:             "TargetClusterUnavailable",
+		Message:            err.Error(),
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+	return fmt.Errorf("resolving target cluster: %w", err)
+}
+
+// checkTargetClusterReachable reports the health of the target cluster
+// in the TargetClusterReachable condition.
+// Returns the time to wait before retrying, if the target cluster is unreachable.
+func (c *PermissionClaimController) checkTargetClusterReachable(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+	cluster *targetclusters.Cluster,
+) (retryAfter time.Duration, reachable bool) {
+	err := cluster.CheckHealth(ctx)
+	if err == nil {
+		meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               permissionsv1alpha1.PermissionClaimTargetClusterReachable,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Reachable",
+			ObservedGeneration: claim.Generation,
+		})
+		return 0, true
+	}
+
+	if !meta.IsStatusConditionFalse(
+		claim.Status.Conditions, permissionsv1alpha1.PermissionClaimTargetClusterReachable) {
+		c.recorder.Event(claim, corev1.EventTypeWarning, eventReasonTargetClusterUnreachable, err.Error())
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimTargetClusterReachable,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Unreachable",
+		Message:            err.Error(),
+		ObservedGeneration: claim.Generation,
+	})
+	cond := meta.FindStatusCondition(
+		claim.Status.Conditions, permissionsv1alpha1.PermissionClaimTargetClusterReachable)
+	retryAfter = targetclusters.UnreachableBackoff(cond.LastTransitionTime.Time, time.Now())
+
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               permissionsv1alpha1.PermissionClaimBound,
+		Status:             metav1.ConditionFalse,
+		Reason:             "TargetClusterUnreachable",
+		Message:            fmt.Sprintf("Target cluster is unreachable, retrying in %s.", retryAfter),
+		ObservedGeneration: claim.Generation,
+	})
+	claim.Status.Phase = permissionsv1alpha1.PermissionClaimPhasePending
+	return retryAfter, false
+}
+
+// targetClusterForCleanup returns the target cluster to clean up a deleted claim on.
+// Returns nil if the referenced TargetCluster no longer exists,
+// TargetClusters are only removed after all claims referencing them are gone.
+func (c *PermissionClaimController) targetClusterForCleanup(
+	ctx context.Context, claim *permissionsv1alpha1.PermissionClaim,
+) (*targetclusters.Cluster, error) {
+	name := targetClusterName(claim)
+	cluster, err := c.targetClusters.Get(name)
+	if err == nil {
+		return cluster, nil
+	}
+	if len(name) == 0 {
+		return nil, fmt.Errorf("resolving target cluster: %w", err)
+	}
+
+	targetCluster := &permissionsv1alpha1.TargetCluster{}
+	if gerr := c.client.Get(ctx, client.ObjectKey{Name: name}, targetCluster); errors.IsNotFound(gerr) {
+		return nil, nil
+	} else if gerr != nil {
+		return nil, fmt.Errorf("getting TargetCluster: %w", gerr)
+	}
+	return nil, fmt.Errorf("resolving target cluster: %w", err)
+}
+
+// enqueueClaimsForTargetCluster maps TargetCluster events
+// to all PermissionClaims referencing the TargetCluster.
+func (c *PermissionClaimController) enqueueClaimsForTargetCluster(obj client.Object) []reconcile.Request {
+	claimList := &permissionsv1alpha1.PermissionClaimList{}
+	if err := c.client.List(context.Background(), claimList); err != nil {
+		c.log.Error(err, "listing PermissionClaims for TargetCluster event", "TargetCluster", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, claim := range claimList.Items {
+		if targetClusterName(&claim) != obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&claim),
+		})
+	}
+	return requests
+}
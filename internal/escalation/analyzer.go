@@ -0,0 +1,173 @@
+// Package escalation detects privilege escalation paths in the permissions requested by PermissionClaims.
+package escalation
+
+import (
+	"fmt"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/rbacrules"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// DefaultPrivilegedNamespaces are namespaces in which running workloads
+// is treated as equivalent to cluster-admin access.
+var DefaultPrivilegedNamespaces = []string{"kube-system"}
+
+// Analyzer inspects PermissionClaims for privilege escalation paths.
+type Analyzer struct {
+	// Namespaces in which namespaced findings are raised to Critical.
+	PrivilegedNamespaces []string
+}
+
+func NewAnalyzer(privilegedNamespaces []string) *Analyzer {
+	return &Analyzer{
+		PrivilegedNamespaces: privilegedNamespaces,
+	}
+}
+
+// Analyze returns a RiskAssessment for the rules and role references of the claim.
+func (a *Analyzer) Analyze(claim *permissionsv1alpha1.PermissionClaim) *permissionsv1alpha1.RiskAssessment {
+	var findings []permissionsv1alpha1.RiskFinding
+
+	namespaces := claimNamespaces(claim)
+	for i, rule := range claim.Spec.Rules {
+		findings = append(findings,
+			a.analyzeRule(fmt.Sprintf("spec.rules[%d]", i), rule, namespaces)...)
+	}
+	for i, nsRules := range claim.Spec.NamespacedRules {
+		for j, rule := range nsRules.Rules {
+			findings = append(findings, a.analyzeRule(
+				fmt.Sprintf("spec.namespacedRules[%d].rules[%d]", i, j), rule, []string{nsRules.Namespace})...)
+		}
+	}
+	for i, rule := range claim.Spec.ClusterRules {
+		findings = append(findings,
+			a.analyzeRule(fmt.Sprintf("spec.clusterRules[%d]", i), rule, nil)...)
+	}
+
+	for i, role := range claim.Spec.RoleRefs {
+		if sev, ok := knownRoleSeverity(role, false); ok {
+			findings = append(findings, permissionsv1alpha1.RiskFinding{
+				Severity: a.raiseForNamespaces(sev, namespaces),
+				Type:     "PrivilegedRoleRef",
+				Field:    fmt.Sprintf("spec.roleRefs[%d]", i),
+				Message:  fmt.Sprintf("binds the well-known ClusterRole %q", role),
+			})
+		}
+	}
+	for i, role := range claim.Spec.ClusterRoleRefs {
+		if sev, ok := knownRoleSeverity(role, true); ok {
+			findings = append(findings, permissionsv1alpha1.RiskFinding{
+				Severity: sev,
+				Type:     "PrivilegedRoleRef",
+				Field:    fmt.Sprintf("spec.clusterRoleRefs[%d]", i),
+				Message:  fmt.Sprintf("binds the well-known ClusterRole %q cluster-wide", role),
+			})
+		}
+	}
+
+	assessment := &permissionsv1alpha1.RiskAssessment{
+		Severity: permissionsv1alpha1.RiskSeverityNone,
+		Findings: findings,
+	}
+	for _, f := range findings {
+		if Exceeds(f.Severity, assessment.Severity) {
+			assessment.Severity = f.Severity
+		}
+	}
+	return assessment
+}
+
+// Analyzes a single rule.
+// namespaces is nil for cluster-scoped rules.
+func (a *Analyzer) analyzeRule(
+	field string, rule rbacv1.PolicyRule, namespaces []string,
+) []permissionsv1alpha1.RiskFinding {
+	// A rule restricted to resourceNames is still an escalation path,
+	// e.g. "bind" on the "cluster-admin" ClusterRole.
+	rule = *rule.DeepCopy()
+	rule.ResourceNames = nil
+
+	var findings []permissionsv1alpha1.RiskFinding
+	for _, p := range paths {
+		if !grantsAny(rule, p.grants) {
+			continue
+		}
+
+		sev := p.clusterSeverity
+		if namespaces != nil {
+			sev = a.raiseForNamespaces(p.namespacedSeverity, namespaces)
+		}
+		if sev == permissionsv1alpha1.RiskSeverityNone {
+			continue
+		}
+		findings = append(findings, permissionsv1alpha1.RiskFinding{
+			Severity: sev,
+			Type:     p.name,
+			Field:    field,
+			Message:  fmt.Sprintf("%s: %s", p.message, rbacrules.String(rule)),
+		})
+	}
+	return findings
+}
+
+// Raises the severity of a namespaced finding to Critical,
+// if it applies to a privileged namespace.
+func (a *Analyzer) raiseForNamespaces(
+	sev permissionsv1alpha1.RiskSeverity, namespaces []string,
+) permissionsv1alpha1.RiskSeverity {
+	if sev == permissionsv1alpha1.RiskSeverityNone {
+		return sev
+	}
+	for _, ns := range namespaces {
+		if slices.Contains(a.PrivilegedNamespaces, ns) {
+			return permissionsv1alpha1.RiskSeverityCritical
+		}
+	}
+	return sev
+}
+
+var severityRank = map[permissionsv1alpha1.RiskSeverity]int{
+	permissionsv1alpha1.RiskSeverityNone:     0,
+	permissionsv1alpha1.RiskSeverityLow:      1,
+	permissionsv1alpha1.RiskSeverityMedium:   2,
+	permissionsv1alpha1.RiskSeverityHigh:     3,
+	permissionsv1alpha1.RiskSeverityCritical: 4,
+}
+
+// Exceeds reports whether severity a is higher than b.
+func Exceeds(a, b permissionsv1alpha1.RiskSeverity) bool {
+	return severityRank[a] > severityRank[b]
+}
+
+// AtLeast reports whether severity a is equal to or higher than b.
+func AtLeast(a, b permissionsv1alpha1.RiskSeverity) bool {
+	return severityRank[a] >= severityRank[b]
+}
+
+// ParseSeverity validates the given string as RiskSeverity.
+func ParseSeverity(s string) (permissionsv1alpha1.RiskSeverity, error) {
+	sev := permissionsv1alpha1.RiskSeverity(s)
+	if _, ok := severityRank[sev]; !ok {
+		return "", fmt.Errorf("unknown risk severity %q", s)
+	}
+	return sev, nil
+}
+
+// Namespaces .spec.rules are applied to.
+func claimNamespaces(claim *permissionsv1alpha1.PermissionClaim) []string {
+	namespaces := []string{claim.Spec.Namespace}
+	namespaces = append(namespaces, claim.Spec.Namespaces...)
+	namespaces = append(namespaces, claim.Status.MatchedNamespaces...)
+	return namespaces
+}
+
+func grantsAny(rule rbacv1.PolicyRule, grants []rbacv1.PolicyRule) bool {
+	for _, g := range grants {
+		if covered, _ := rbacrules.Covers([]rbacv1.PolicyRule{rule}, []rbacv1.PolicyRule{g}); covered {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package escalation
+
+import (
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// escalationPath describes a set of permissions that allow
+// gaining privileges beyond the claimed rules.
+type escalationPath struct {
+	name    string
+	message string
+	// The path is found if a rule grants any of these permissions.
+	grants []rbacv1.PolicyRule
+	// Severity when granted via ClusterRules.
+	clusterSeverity permissionsv1alpha1.RiskSeverity
+	// Severity when granted within namespaces.
+	namespacedSeverity permissionsv1alpha1.RiskSeverity
+}
+
+var paths = []escalationPath{
+	{
+		name:    "Wildcard",
+		message: "grants all verbs on all resources",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityHigh,
+	},
+	{
+		name:    "Impersonation",
+		message: "allows acting as other users, groups or ServiceAccounts",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"impersonate"}, APIGroups: []string{""}, Resources: []string{"users"}},
+			{Verbs: []string{"impersonate"}, APIGroups: []string{""}, Resources: []string{"groups"}},
+			{Verbs: []string{"impersonate"}, APIGroups: []string{""}, Resources: []string{"serviceaccounts"}},
+			{Verbs: []string{"impersonate"}, APIGroups: []string{"authentication.k8s.io"}, Resources: []string{"userextras/scopes"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityHigh,
+	},
+	{
+		name:    "RoleEscalation",
+		message: "allows granting permissions not held via Roles or ClusterRoles",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"escalate"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"roles"}},
+			{Verbs: []string{"escalate"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterroles"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityHigh,
+	},
+	{
+		name:    "RoleBinding",
+		message: "allows binding Roles or ClusterRoles with permissions not held",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"bind"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"roles"}},
+			{Verbs: []string{"bind"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterroles"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityHigh,
+	},
+	{
+		name:    "BindingManagement",
+		message: "allows creating and changing RoleBindings or ClusterRoleBindings",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"create"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"rolebindings"}},
+			{Verbs: []string{"update"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"rolebindings"}},
+			{Verbs: []string{"patch"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"rolebindings"}},
+			{Verbs: []string{"create"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterrolebindings"}},
+			{Verbs: []string{"update"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterrolebindings"}},
+			{Verbs: []string{"patch"}, APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterrolebindings"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityMedium,
+	},
+	{
+		name:    "WorkloadCreation",
+		message: "allows running workloads as any ServiceAccount in reach",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"apps"}, Resources: []string{"statefulsets"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"apps"}, Resources: []string{"replicasets"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"batch"}, Resources: []string{"jobs"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityMedium,
+	},
+	{
+		name:    "PodExec",
+		message: "allows executing commands in running containers",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods/exec"}},
+			{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods/attach"}},
+			{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods/exec"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityMedium,
+	},
+	{
+		name:    "SecretAccess",
+		message: "allows reading Secrets, including ServiceAccount tokens",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+			{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+			{Verbs: []string{"watch"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityMedium,
+	},
+	{
+		name:    "TokenCreation",
+		message: "allows requesting tokens for ServiceAccounts",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"serviceaccounts/token"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityMedium,
+	},
+	{
+		name:    "NodeProxy",
+		message: "allows direct access to the kubelet API",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"nodes/proxy"}},
+			{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"nodes/proxy"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityCritical,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityNone,
+	},
+	{
+		name:    "AdmissionControl",
+		message: "allows intercepting and mutating API requests via admission webhooks",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"create"}, APIGroups: []string{"admissionregistration.k8s.io"}, Resources: []string{"mutatingwebhookconfigurations"}},
+			{Verbs: []string{"update"}, APIGroups: []string{"admissionregistration.k8s.io"}, Resources: []string{"mutatingwebhookconfigurations"}},
+			{Verbs: []string{"create"}, APIGroups: []string{"admissionregistration.k8s.io"}, Resources: []string{"validatingwebhookconfigurations"}},
+			{Verbs: []string{"update"}, APIGroups: []string{"admissionregistration.k8s.io"}, Resources: []string{"validatingwebhookconfigurations"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityNone,
+	},
+	{
+		name:    "CertificateApproval",
+		message: "allows approving CertificateSigningRequests",
+		grants: []rbacv1.PolicyRule{
+			{Verbs: []string{"update"}, APIGroups: []string{"certificates.k8s.io"}, Resources: []string{"certificatesigningrequests/approval"}},
+		},
+		clusterSeverity:    permissionsv1alpha1.RiskSeverityHigh,
+		namespacedSeverity: permissionsv1alpha1.RiskSeverityNone,
+	},
+}
+
+// Returns the severity of binding one of the default user-facing ClusterRoles.
+func knownRoleSeverity(role string, clusterWide bool) (permissionsv1alpha1.RiskSeverity, bool) {
+	switch role {
+	case "cluster-admin", "admin":
+		if clusterWide {
+			return permissionsv1alpha1.RiskSeverityCritical, true
+		}
+		return permissionsv1alpha1.RiskSeverityHigh, true
+	case "edit":
+		if clusterWide {
+			return permissionsv1alpha1.RiskSeverityHigh, true
+		}
+		return permissionsv1alpha1.RiskSeverityMedium, true
+	}
+	return "", false
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/utils/strings/slices"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	claimsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "claims"),
+		"Number of PermissionClaims by phase.",
+		[]string{"phase"}, nil)
+	claimRulesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "claim", "rules"),
+		"Number of rules granted by a PermissionClaim by scope.",
+		[]string{"namespace", "name", "scope"}, nil)
+	claimWildcardRulesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "claim", "wildcard_rules"),
+		`Number of rules granted by a PermissionClaim using "*" in verbs, apiGroups, resources or nonResourceURLs.`,
+		[]string{"namespace", "name"}, nil)
+	claimCredentialsAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "claim", "credentials_age_seconds"),
+		"Seconds since the current credentials of a PermissionClaim were issued.",
+		[]string{"namespace", "name"}, nil)
+	claimCredentialsExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "claim", "credentials_expiry_seconds"),
+		"Seconds until the current credentials of a PermissionClaim expire. Not reported for non-expiring credentials.",
+		[]string{"namespace", "name"}, nil)
+)
+
+// Phases always reported by the claims metric, even if no claim is in them.
+var knownPhases = []permissionsv1alpha1.PermissionClaimPhase{
+	permissionsv1alpha1.PermissionClaimPhasePending,
+	permissionsv1alpha1.PermissionClaimPhasePendingApproval,
+	permissionsv1alpha1.PermissionClaimPhaseBound,
+	permissionsv1alpha1.PermissionClaimPhaseDenied,
+	permissionsv1alpha1.PermissionClaimPhaseExpired,
+	permissionsv1alpha1.PermissionClaimPhaseSuspended,
+	permissionsv1alpha1.PermissionClaimPhaseTerminating,
+}
+
+// ClaimsCollector reports metrics about all PermissionClaims on every scrape.
+type ClaimsCollector struct {
+	client client.Reader
+}
+
+var _ prometheus.Collector = (*ClaimsCollector)(nil)
+
+// NewClaimsCollector returns a ClaimsCollector reading PermissionClaims from the given client.
+// The client should be backed by a cache, as it is used on every scrape.
+func NewClaimsCollector(client client.Reader) *ClaimsCollector {
+	return &ClaimsCollector{client: client}
+}
+
+func (c *ClaimsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- claimsDesc
+	ch <- claimRulesDesc
+	ch <- claimWildcardRulesDesc
+	ch <- claimCredentialsAgeDesc
+	ch <- claimCredentialsExpiryDesc
+}
+
+func (c *ClaimsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	claimList := &permissionsv1alpha1.PermissionClaimList{}
+	if err := c.client.List(ctx, claimList); err != nil {
+		ctrl.Log.WithName("metrics").Error(err, "listing PermissionClaims")
+		return
+	}
+
+	now := time.Now()
+	phases := map[permissionsv1alpha1.PermissionClaimPhase]int{}
+	for _, phase := range knownPhases {
+		phases[phase] = 0
+	}
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		phase := claim.Status.Phase
+		if len(phase) == 0 {
+			phase = permissionsv1alpha1.PermissionClaimPhasePending
+		}
+		phases[phase]++
+
+		var namespacedRules []rbacv1.PolicyRule
+		namespacedRules = append(namespacedRules, claim.Spec.Rules...)
+		for _, nsRules := range claim.Spec.NamespacedRules {
+			namespacedRules = append(namespacedRules, nsRules.Rules...)
+		}
+		ch <- prometheus.MustNewConstMetric(claimRulesDesc, prometheus.GaugeValue,
+			float64(len(namespacedRules)), claim.Namespace, claim.Name, "namespaced")
+		ch <- prometheus.MustNewConstMetric(claimRulesDesc, prometheus.GaugeValue,
+			float64(len(claim.Spec.ClusterRules)), claim.Namespace, claim.Name, "cluster")
+		ch <- prometheus.MustNewConstMetric(claimWildcardRulesDesc, prometheus.GaugeValue,
+			float64(countWildcardRules(namespacedRules)+countWildcardRules(claim.Spec.ClusterRules)),
+			claim.Namespace, claim.Name)
+
+		creds := claim.Status.Credentials
+		if creds == nil {
+			continue
+		}
+		if creds.IssueTime != nil {
+			ch <- prometheus.MustNewConstMetric(claimCredentialsAgeDesc, prometheus.GaugeValue,
+				now.Sub(creds.IssueTime.Time).Seconds(), claim.Namespace, claim.Name)
+		}
+		if creds.ExpirationTime != nil {
+			ch <- prometheus.MustNewConstMetric(claimCredentialsExpiryDesc, prometheus.GaugeValue,
+				creds.ExpirationTime.Sub(now).Seconds(), claim.Namespace, claim.Name)
+		}
+	}
+
+	for phase, count := range phases {
+		ch <- prometheus.MustNewConstMetric(claimsDesc, prometheus.GaugeValue,
+			float64(count), string(phase))
+	}
+}
+
+func countWildcardRules(rules []rbacv1.PolicyRule) int {
+	var count int
+	for _, rule := range rules {
+		for _, values := range [][]string{rule.Verbs, rule.APIGroups, rule.Resources, rule.NonResourceURLs} {
+			if slices.Contains(values, "*") {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
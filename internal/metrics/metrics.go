@@ -0,0 +1,56 @@
+// Package metrics contains the Prometheus metrics of the permission-claim-operator.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "permission_claim_operator"
+
+var (
+	// TargetClusterRequestDuration tracks the latency of requests to the target cluster API.
+	// Watch requests are excluded, as they are long-running.
+	TargetClusterRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "target_cluster",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the target cluster API by verb and resource.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"verb", "resource"})
+
+	// TargetClusterRequestErrors counts failed requests to the target cluster API.
+	TargetClusterRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "target_cluster",
+		Name:      "request_errors_total",
+		Help: "Failed requests to the target cluster API by verb, resource and status code. " +
+			`Connection errors use code "error", NotFound and Conflict responses are not counted.`,
+	}, []string{"verb", "resource", "code"})
+
+	// TargetClusterUp reports whether the API of a target cluster was reachable on the last health check.
+	TargetClusterUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "target_cluster",
+		Name:      "up",
+		Help: "Whether the target cluster API was reachable on the last health check (1) or not (0). " +
+			"The cluster label is empty for the default target cluster.",
+	}, []string{"cluster"})
+
+	// DriftCorrections counts objects on the target cluster that were changed
+	// outside of the operator and had to be reverted.
+	DriftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_corrections_total",
+		Help:      "Objects on the target cluster reverted to their desired state after being changed externally.",
+	}, []string{"kind"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		TargetClusterRequestDuration,
+		TargetClusterRequestErrors,
+		TargetClusterUp,
+		DriftCorrections,
+	)
+}
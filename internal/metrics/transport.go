@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InstrumentTransport wraps the given RoundTripper to record
+// latency and errors of requests to the target cluster API.
+// Use with rest.Config.Wrap.
+func InstrumentTransport(rt http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{next: rt}
+}
+
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, resource := requestVerbAndResource(req)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if verb != "watch" {
+		TargetClusterRequestDuration.
+			WithLabelValues(verb, resource).
+			Observe(time.Since(start).Seconds())
+	}
+
+	switch {
+	case err != nil:
+		TargetClusterRequestErrors.WithLabelValues(verb, resource, "error").Inc()
+	case resp.StatusCode >= 400 &&
+		resp.StatusCode != http.StatusNotFound &&
+		resp.StatusCode != http.StatusConflict:
+		TargetClusterRequestErrors.
+			WithLabelValues(verb, resource, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// Derives the Kubernetes API verb and resource from a request.
+// e.g. "GET /api/v1/namespaces/default/secrets/test" => "get", "secrets".
+func requestVerbAndResource(req *http.Request) (verb, resource string) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		// /api/v1/...
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		// /apis/group/version/...
+		parts = parts[3:]
+	default:
+		// discovery and other non-resource requests
+		return strings.ToLower(req.Method), "nonResource"
+	}
+
+	// namespaced requests, but not requests to the namespaces resource itself.
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+
+	var hasName bool
+	switch len(parts) {
+	case 0:
+		resource = "unknown"
+	case 1:
+		resource = parts[0]
+	case 2:
+		resource, hasName = parts[0], true
+	default:
+		resource, hasName = parts[0]+"/"+parts[2], true
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		switch {
+		case req.URL.Query().Get("watch") == "true":
+			verb = "watch"
+		case hasName:
+			verb = "get"
+		default:
+			verb = "list"
+		}
+	case http.MethodPost:
+		verb = "create"
+	case http.MethodPut:
+		verb = "update"
+	case http.MethodPatch:
+		verb = "patch"
+	case http.MethodDelete:
+		verb = "delete"
+	default:
+		verb = strings.ToLower(req.Method)
+	}
+	return verb, resource
+}
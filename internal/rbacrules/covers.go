@@ -0,0 +1,117 @@
+// Package rbacrules contains helpers to compare and analyze RBAC PolicyRules.
+package rbacrules
+
+import (
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+// Covers reports whether the ownerRules grant every permission in requestedRules.
+// Returns the uncovered parts of requestedRules, broken down into single-verb rules.
+func Covers(ownerRules, requestedRules []rbacv1.PolicyRule) (bool, []rbacv1.PolicyRule) {
+	var uncovered []rbacv1.PolicyRule
+	for _, requested := range requestedRules {
+		for _, atomic := range Breakdown(requested) {
+			if !anyRuleCovers(ownerRules, atomic) {
+				uncovered = append(uncovered, atomic)
+			}
+		}
+	}
+	return len(uncovered) == 0, uncovered
+}
+
+// Breakdown splits a PolicyRule into rules with a single
+// verb, apiGroup, resource and resourceName or nonResourceURL each.
+func Breakdown(rule rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for _, verb := range rule.Verbs {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				if len(rule.ResourceNames) == 0 {
+					rules = append(rules, rbacv1.PolicyRule{
+						Verbs:     []string{verb},
+						APIGroups: []string{group},
+						Resources: []string{resource},
+					})
+					continue
+				}
+				for _, name := range rule.ResourceNames {
+					rules = append(rules, rbacv1.PolicyRule{
+						Verbs:         []string{verb},
+						APIGroups:     []string{group},
+						Resources:     []string{resource},
+						ResourceNames: []string{name},
+					})
+				}
+			}
+		}
+		for _, url := range rule.NonResourceURLs {
+			rules = append(rules, rbacv1.PolicyRule{
+				Verbs:           []string{verb},
+				NonResourceURLs: []string{url},
+			})
+		}
+	}
+	return rules
+}
+
+func anyRuleCovers(ownerRules []rbacv1.PolicyRule, atomic rbacv1.PolicyRule) bool {
+	for _, owner := range ownerRules {
+		if ruleCovers(owner, atomic) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleCovers reports whether owner grants everything in the given atomic rule.
+// Mirrors the semantics of the RBAC authorizer.
+func ruleCovers(owner, atomic rbacv1.PolicyRule) bool {
+	if !matchesAny(owner.Verbs, atomic.Verbs[0]) {
+		return false
+	}
+
+	if len(atomic.NonResourceURLs) > 0 {
+		return nonResourceURLCovers(owner.NonResourceURLs, atomic.NonResourceURLs[0])
+	}
+
+	if !matchesAny(owner.APIGroups, atomic.APIGroups[0]) ||
+		!resourceCovers(owner.Resources, atomic.Resources[0]) {
+		return false
+	}
+	if len(owner.ResourceNames) == 0 {
+		return true
+	}
+	return len(atomic.ResourceNames) == 1 &&
+		slices.Contains(owner.ResourceNames, atomic.ResourceNames[0])
+}
+
+func matchesAny(owner []string, requested string) bool {
+	return slices.Contains(owner, rbacv1.VerbAll) || slices.Contains(owner, requested)
+}
+
+func resourceCovers(ownerResources []string, requested string) bool {
+	if slices.Contains(ownerResources, rbacv1.ResourceAll) || slices.Contains(ownerResources, requested) {
+		return true
+	}
+	// "*/subresource" covers a subresource of every resource.
+	if i := strings.Index(requested, "/"); i != -1 {
+		return slices.Contains(ownerResources, "*"+requested[i:])
+	}
+	return false
+}
+
+func nonResourceURLCovers(ownerURLs []string, requested string) bool {
+	for _, owner := range ownerURLs {
+		if owner == rbacv1.NonResourceAll || owner == requested {
+			return true
+		}
+		if strings.HasSuffix(owner, "*") &&
+			strings.HasPrefix(requested, strings.TrimSuffix(owner, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package rbacrules
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestCovers(t *testing.T) {
+	podsRead := rbacv1.PolicyRule{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"get", "list"},
+	}
+
+	tests := []struct {
+		name          string
+		owner         []rbacv1.PolicyRule
+		requested     []rbacv1.PolicyRule
+		wantCovered   bool
+		wantUncovered []rbacv1.PolicyRule
+	}{
+		{
+			name:        "nothing requested",
+			wantCovered: true,
+		},
+		{
+			name:        "exact match",
+			owner:       []rbacv1.PolicyRule{podsRead},
+			requested:   []rbacv1.PolicyRule{podsRead},
+			wantCovered: true,
+		},
+		{
+			name:  "missing verb",
+			owner: []rbacv1.PolicyRule{podsRead},
+			requested: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get", "delete"},
+			}},
+			wantUncovered: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"delete"},
+			}},
+		},
+		{
+			name: "split across owner rules",
+			owner: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+			},
+			requested:   []rbacv1.PolicyRule{podsRead},
+			wantCovered: true,
+		},
+		{
+			name: "wildcard verbs, groups and resources",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				APIGroups: []string{"apps"},
+				Resources: []string{"deployments", "deployments/scale"},
+				Verbs:     []string{"update"},
+			}},
+			wantCovered: true,
+		},
+		{
+			name: "requested wildcard is only covered by wildcard",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "secrets"},
+				Verbs:     []string{"get"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"*"},
+				Verbs:     []string{"get"},
+			}},
+			wantUncovered: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"*"},
+				Verbs:     []string{"get"},
+			}},
+		},
+		{
+			name: "subresource wildcard",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups: []string{"apps"},
+				Resources: []string{"*/scale"},
+				Verbs:     []string{"update"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				APIGroups: []string{"apps"},
+				Resources: []string{"deployments/scale", "deployments"},
+				Verbs:     []string{"update"},
+			}},
+			wantUncovered: []rbacv1.PolicyRule{{
+				APIGroups: []string{"apps"},
+				Resources: []string{"deployments"},
+				Verbs:     []string{"update"},
+			}},
+		},
+		{
+			name: "resourceNames restrict the owner",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups:     []string{""},
+				Resources:     []string{"configmaps"},
+				ResourceNames: []string{"a", "b"},
+				Verbs:         []string{"get"},
+			}},
+			requested: []rbacv1.PolicyRule{
+				{
+					APIGroups:     []string{""},
+					Resources:     []string{"configmaps"},
+					ResourceNames: []string{"a", "c"},
+					Verbs:         []string{"get"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"configmaps"},
+					Verbs:     []string{"get"},
+				},
+			},
+			wantUncovered: []rbacv1.PolicyRule{
+				{
+					APIGroups:     []string{""},
+					Resources:     []string{"configmaps"},
+					ResourceNames: []string{"c"},
+					Verbs:         []string{"get"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"configmaps"},
+					Verbs:     []string{"get"},
+				},
+			},
+		},
+		{
+			name: "owner without resourceNames covers requested names",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+				Verbs:     []string{"get"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				APIGroups:     []string{""},
+				Resources:     []string{"configmaps"},
+				ResourceNames: []string{"a"},
+				Verbs:         []string{"get"},
+			}},
+			wantCovered: true,
+		},
+		{
+			name: "nonResourceURLs",
+			owner: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/healthz", "/metrics/*"},
+				Verbs:           []string{"get"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/healthz", "/metrics/cadvisor", "/version"},
+				Verbs:           []string{"get"},
+			}},
+			wantUncovered: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/version"},
+				Verbs:           []string{"get"},
+			}},
+		},
+		{
+			name: "nonResourceURL wildcard",
+			owner: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"*"},
+				Verbs:           []string{"get"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/version"},
+				Verbs:           []string{"get"},
+			}},
+			wantCovered: true,
+		},
+		{
+			name: "resource rules do not cover nonResourceURLs",
+			owner: []rbacv1.PolicyRule{{
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			}},
+			requested: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/version"},
+				Verbs:           []string{"get"},
+			}},
+			wantUncovered: []rbacv1.PolicyRule{{
+				NonResourceURLs: []string{"/version"},
+				Verbs:           []string{"get"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			covered, uncovered := Covers(test.owner, test.requested)
+			if covered != test.wantCovered {
+				t.Errorf("covered = %v, want %v", covered, test.wantCovered)
+			}
+			if !reflect.DeepEqual(uncovered, test.wantUncovered) {
+				t.Errorf("uncovered = %v, want %v", uncovered, test.wantUncovered)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package rbacrules
+
+import (
+	"fmt"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// String returns a compact human readable representation of the rule.
+func String(rule rbacv1.PolicyRule) string {
+	var parts []string
+	if len(rule.APIGroups) > 0 {
+		parts = append(parts, fmt.Sprintf("apiGroups=%q", rule.APIGroups))
+	}
+	if len(rule.Resources) > 0 {
+		parts = append(parts, fmt.Sprintf("resources=%q", rule.Resources))
+	}
+	if len(rule.ResourceNames) > 0 {
+		parts = append(parts, fmt.Sprintf("resourceNames=%q", rule.ResourceNames))
+	}
+	if len(rule.NonResourceURLs) > 0 {
+		parts = append(parts, fmt.Sprintf("nonResourceURLs=%q", rule.NonResourceURLs))
+	}
+	parts = append(parts, fmt.Sprintf("verbs=%q", rule.Verbs))
+	return strings.Join(parts, " ")
+}
+
+// Strings returns a compact human readable representation of the rules.
+func Strings(rules []rbacv1.PolicyRule) string {
+	s := make([]string, len(rules))
+	for i := range rules {
+		s[i] = "{" + String(rules[i]) + "}"
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,83 @@
+package targetclusters
+
+import (
+	"fmt"
+
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Cluster bundles the clients to manage permissions on a single target cluster.
+type Cluster struct {
+	// Name of the TargetCluster object, empty for the default target cluster.
+	Name string
+	// Client reading from Cache.
+	Client client.Client
+	Cache  cache.Cache
+	// Used to request tokens via the TokenRequest API.
+	ServiceAccounts corev1client.ServiceAccountsGetter
+	// Template to render kubeconfigs for PermissionClaims from.
+	TemplateKubeconfig *clientcmdapi.Config
+
+	discovery rest.Interface
+	health    healthCheck
+	// sources watching the cluster, nil for the default cluster.
+	sources *sourceSet
+}
+
+// NewCluster sets up clients for the cluster reachable via the given config.
+// The returned cache still has to be started.
+func NewCluster(
+	name string, cfg *rest.Config, scheme *runtime.Scheme,
+	templateKubeconfig *clientcmdapi.Config,
+) (*Cluster, error) {
+	cfg = rest.CopyConfig(cfg)
+	cfg.Wrap(metrics.InstrumentTransport)
+
+	mapper, err := apiutil.NewDiscoveryRESTMapper(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating target cluster rest mapper: %w", err)
+	}
+	uncachedClient, err := client.New(cfg, client.Options{
+		Scheme: scheme,
+		Mapper: mapper,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating target cluster client: %w", err)
+	}
+	c, err := cache.New(cfg, cache.Options{
+		Scheme: scheme,
+		Mapper: mapper,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating target cluster cache: %w", err)
+	}
+	cachedClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader: c,
+		Client:      uncachedClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating cached client for target cluster: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating target cluster clientset: %w", err)
+	}
+
+	return &Cluster{
+		Name:               name,
+		Client:             cachedClient,
+		Cache:              c,
+		ServiceAccounts:    clientset.CoreV1(),
+		TemplateKubeconfig: templateKubeconfig,
+
+		discovery: clientset.Discovery().RESTClient(),
+	}, nil
+}
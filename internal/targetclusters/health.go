@@ -0,0 +1,88 @@
+package targetclusters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+)
+
+const (
+	// Results of health checks are reused for this long,
+	// to not probe the target cluster on every reconcile.
+	healthCheckTTL = 10 * time.Second
+	// Time to wait for the target cluster to respond.
+	healthCheckTimeout = 5 * time.Second
+
+	// Bounds of the backoff between attempts to reach an unreachable target cluster.
+	minUnreachableBackoff = 5 * time.Second
+	maxUnreachableBackoff = 5 * time.Minute
+)
+
+type healthCheck struct {
+	mux       sync.Mutex
+	lastCheck time.Time
+	lastErr   error
+}
+
+// CheckHealth verifies that the API of the cluster is reachable and its cache has synced.
+// The result is also reported via the target cluster up metric.
+func (c *Cluster) CheckHealth(ctx context.Context) error {
+	c.health.mux.Lock()
+	defer c.health.mux.Unlock()
+	if time.Since(c.health.lastCheck) < healthCheckTTL {
+		return c.health.lastErr
+	}
+
+	c.health.lastErr = c.checkHealth(ctx)
+	c.health.lastCheck = time.Now()
+	up := 1.0
+	if c.health.lastErr != nil {
+		up = 0
+	}
+	metrics.TargetClusterUp.WithLabelValues(c.Name).Set(up)
+	return c.health.lastErr
+}
+
+func (c *Cluster) checkHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	// /version is readable by everyone, so this works regardless of the permissions of the operator.
+	if err := c.discovery.Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("target cluster API is unreachable: %w", err)
+	}
+	if !c.Cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("target cluster cache has not synced")
+	}
+	return nil
+}
+
+// ReadyzCheck reports the health of the default target cluster,
+// which the operator can not work without.
+// TargetClusters report their health in status instead,
+// so a single unreachable cluster of a fleet does not take the operator out of service.
+func (p *Pool) ReadyzCheck(req *http.Request) error {
+	if p.defaultCluster == nil {
+		return nil
+	}
+	return p.defaultCluster.CheckHealth(req.Context())
+}
+
+// UnreachableBackoff returns the time to wait before trying to reach a target cluster again,
+// that has been unreachable since the given time.
+// The backoff doubles with every attempt and only changes in steps,
+// so status messages mentioning it stay stable between attempts.
+func UnreachableBackoff(since, now time.Time) time.Duration {
+	backoff := minUnreachableBackoff
+	for backoff < maxUnreachableBackoff && 2*backoff <= now.Sub(since) {
+		backoff *= 2
+	}
+	if backoff > maxUnreachableBackoff {
+		return maxUnreachableBackoff
+	}
+	return backoff
+}
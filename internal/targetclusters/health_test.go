@@ -0,0 +1,32 @@
+package targetclusters
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnreachableBackoff(t *testing.T) {
+	since := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		unreachable time.Duration
+		want        time.Duration
+	}{
+		{name: "just became unreachable", unreachable: 0, want: 5 * time.Second},
+		{name: "before first step", unreachable: 9 * time.Second, want: 5 * time.Second},
+		{name: "first step", unreachable: 10 * time.Second, want: 10 * time.Second},
+		{name: "between steps", unreachable: 30 * time.Second, want: 20 * time.Second},
+		{name: "later step", unreachable: 2 * time.Minute, want: 80 * time.Second},
+		{name: "capped", unreachable: 10 * time.Minute, want: 5 * time.Minute},
+		{name: "capped long after", unreachable: 24 * time.Hour, want: 5 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := UnreachableBackoff(since, since.Add(test.unreachable))
+			if got != test.want {
+				t.Errorf("UnreachableBackoff() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package targetclusters
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/thetechnick/permission-claim-operator/internal/metrics"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ErrNotFound is returned for target clusters that are not part of the pool.
+var ErrNotFound = errors.New("target cluster not found")
+
+// Time to wait for the cache of a newly added cluster to sync.
+const cacheSyncTimeout = 30 * time.Second
+
+// Pool maintains clients for all target clusters.
+// Clusters are added, refreshed and removed while the operator is running,
+// the default target cluster is configured at startup and stays for the lifetime of the pool.
+type Pool struct {
+	log            logr.Logger
+	scheme         *runtime.Scheme
+	defaultCluster *Cluster
+
+	mux      sync.RWMutex
+	ctx      context.Context
+	clusters map[string]*poolEntry
+	onAdd    []func(*Cluster) error
+	// sources watching each cluster name, kept across refreshes and removal.
+	sources map[string]*sourceSet
+}
+
+type poolEntry struct {
+	cluster *Cluster
+	// hash of the kubeconfigs the cluster was set up from.
+	hash string
+	// stops the cache of the cluster.
+	stop context.CancelFunc
+}
+
+var _ manager.Runnable = (*Pool)(nil)
+
+// NewPool returns a new Pool.
+// defaultCluster may be nil, if no default target cluster is configured.
+// Its cache has to be started by the caller.
+func NewPool(log logr.Logger, scheme *runtime.Scheme, defaultCluster *Cluster) *Pool {
+	return &Pool{
+		log:            log,
+		scheme:         scheme,
+		defaultCluster: defaultCluster,
+		clusters:       map[string]*poolEntry{},
+		sources:        map[string]*sourceSet{},
+	}
+}
+
+// OnClusterAdded registers a function that is called the first time a cluster
+// with a given name is added to the pool, after its cache has synced.
+// Not called again when the cluster is refreshed, sources from Cluster.Kind
+// move to the cache of the refreshed cluster instead. Not called for the default cluster.
+func (p *Pool) OnClusterAdded(fn func(*Cluster) error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.onAdd = append(p.onAdd, fn)
+}
+
+// Start implements manager.Runnable.
+// Clusters can only be added after the pool was started.
+// All caches are stopped when the given context is canceled.
+func (p *Pool) Start(ctx context.Context) error {
+	p.mux.Lock()
+	p.ctx = ctx
+	p.mux.Unlock()
+
+	<-ctx.Done()
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for name, entry := range p.clusters {
+		entry.stop()
+		delete(p.clusters, name)
+	}
+	return nil
+}
+
+// Get returns the cluster with the given name.
+// An empty name returns the default cluster.
+func (p *Pool) Get(name string) (*Cluster, error) {
+	if len(name) == 0 {
+		if p.defaultCluster == nil {
+			return nil, fmt.Errorf("%w: no default target cluster configured", ErrNotFound)
+		}
+		return p.defaultCluster, nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	entry, ok := p.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return entry.cluster, nil
+}
+
+// Ensure adds the cluster with the given name to the pool,
+// or replaces it, when the given kubeconfigs have changed.
+// An empty templateKubeconfig defaults to kubeconfig.
+func (p *Pool) Ensure(
+	ctx context.Context, name string, kubeconfig, templateKubeconfig []byte,
+) (*Cluster, error) {
+	if len(templateKubeconfig) == 0 {
+		templateKubeconfig = kubeconfig
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(append(append([]byte{}, kubeconfig...), templateKubeconfig...)))
+
+	p.mux.RLock()
+	poolCtx := p.ctx
+	entry, ok := p.clusters[name]
+	p.mux.RUnlock()
+	if ok && entry.hash == hash {
+		return entry.cluster, nil
+	}
+	if poolCtx == nil {
+		return nil, fmt.Errorf("target cluster pool not yet started")
+	}
+
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %w", err)
+	}
+	template, err := clientcmd.Load(templateKubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template kubeconfig: %w", err)
+	}
+	cluster, err := NewCluster(name, cfg, p.scheme, template)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheCtx, stop := context.WithCancel(poolCtx)
+	go func() {
+		if err := cluster.Cache.Start(cacheCtx); err != nil {
+			p.log.Error(err, "running cache", "TargetCluster", name)
+		}
+	}()
+	syncCtx, cancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer cancel()
+	if !cluster.Cache.WaitForCacheSync(syncCtx) {
+		stop()
+		return nil, fmt.Errorf("waiting for target cluster cache to sync")
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if sources, watched := p.sources[name]; watched {
+		cluster.sources = sources
+		if err := sources.setCache(cluster.Cache); err != nil {
+			stop()
+			return nil, err
+		}
+	} else {
+		cluster.sources = &sourceSet{cache: cluster.Cache}
+		for _, fn := range p.onAdd {
+			if err := fn(cluster); err != nil {
+				stop()
+				return nil, err
+			}
+		}
+		p.sources[name] = cluster.sources
+	}
+	if old, ok := p.clusters[name]; ok {
+		old.stop()
+	}
+	p.clusters[name] = &poolEntry{
+		cluster: cluster,
+		hash:    hash,
+		stop:    stop,
+	}
+	if ok {
+		p.log.Info("refreshed target cluster", "TargetCluster", name)
+	} else {
+		p.log.Info("added target cluster", "TargetCluster", name)
+	}
+	return cluster, nil
+}
+
+// Remove stops the cache of the cluster with the given name and removes it from the pool.
+func (p *Pool) Remove(name string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	entry, ok := p.clusters[name]
+	if !ok {
+		return
+	}
+	entry.stop()
+	delete(p.clusters, name)
+	metrics.TargetClusterUp.DeleteLabelValues(name)
+	p.log.Info("removed target cluster", "TargetCluster", name)
+}
@@ -0,0 +1,123 @@
+package targetclusters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// sourceSet holds all sources watching a cluster of the pool.
+// It is shared by all generations of a cluster with the same name,
+// so watches survive the cluster being refreshed.
+type sourceSet struct {
+	mux     sync.Mutex
+	cache   cache.Cache
+	sources []*clusterSource
+}
+
+// Kind returns a source for objects of the given type on the cluster.
+// Unlike source.Kind, the source moves to the new cache
+// when the cluster is refreshed with new kubeconfigs.
+func (c *Cluster) Kind(obj client.Object) source.Source {
+	if c.sources == nil {
+		// the default cluster is never refreshed.
+		return source.NewKindWithCache(obj, c.Cache)
+	}
+	return c.sources.add(obj)
+}
+
+func (s *sourceSet) add(obj client.Object) *clusterSource {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	src := &clusterSource{obj: obj, cache: s.cache}
+	s.sources = append(s.sources, src)
+	return src
+}
+
+// setCache moves all sources to the given cache.
+func (s *sourceSet) setCache(c cache.Cache) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.cache = c
+	for _, src := range s.sources {
+		if err := src.setCache(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterSource is a source.Source following the cache of a cluster across refreshes.
+type clusterSource struct {
+	obj client.Object
+
+	mux   sync.Mutex
+	cache cache.Cache
+	// starts a watch on the given cache, nil until the source is started.
+	start func(cache.Cache) error
+	// watch on the current cache and its cancel func.
+	kind   source.SyncingSource
+	cancel context.CancelFunc
+}
+
+var _ source.SyncingSource = (*clusterSource)(nil)
+
+// Start implements source.Source.
+func (s *clusterSource) Start(
+	ctx context.Context, h handler.EventHandler,
+	queue workqueue.RateLimitingInterface, prct ...predicate.Predicate,
+) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.start = func(c cache.Cache) error {
+		kindCtx, cancel := context.WithCancel(ctx)
+		kind := source.NewKindWithCache(s.obj, c)
+		if err := kind.Start(kindCtx, h, queue, prct...); err != nil {
+			cancel()
+			return err
+		}
+		s.kind, s.cancel = kind, cancel
+		return nil
+	}
+	return s.start(s.cache)
+}
+
+// WaitForSync implements source.SyncingSource.
+func (s *clusterSource) WaitForSync(ctx context.Context) error {
+	s.mux.Lock()
+	kind := s.kind
+	s.mux.Unlock()
+	if kind == nil {
+		return fmt.Errorf("%s not started", s)
+	}
+	return kind.WaitForSync(ctx)
+}
+
+func (s *clusterSource) setCache(c cache.Cache) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.cache = c
+	if s.start == nil {
+		return nil
+	}
+	// The old cache is stopped by the pool,
+	// which also stops delivering events from it.
+	s.cancel()
+	// Kind blocks until someone waits for it to report its start.
+	go func(kind source.SyncingSource) { _ = kind.WaitForSync(context.Background()) }(s.kind)
+	if err := s.start(c); err != nil {
+		return fmt.Errorf("watching %T: %w", s.obj, err)
+	}
+	return nil
+}
+
+func (s *clusterSource) String() string {
+	return fmt.Sprintf("target cluster source: %T", s.obj)
+}
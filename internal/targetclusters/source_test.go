@@ -0,0 +1,60 @@
+package targetclusters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+func TestClusterSource_SetCache(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	oldCache := &informertest.FakeInformers{Scheme: scheme}
+	sources := &sourceSet{cache: oldCache}
+	src := (&Cluster{sources: sources}).Kind(&corev1.Secret{})
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	if err := src.Start(ctx, &handler.EnqueueRequestForObject{}, queue); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.(*clusterSource).WaitForSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// refresh
+	newCache := &informertest.FakeInformers{Scheme: scheme}
+	if err := sources.setCache(newCache); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.(*clusterSource).WaitForSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	informer, err := newCache.FakeInformerFor(&corev1.Secret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	informer.Add(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"}})
+	if queue.Len() != 1 {
+		t.Errorf("queue length = %d, want 1 event from the new cache", queue.Len())
+	}
+
+	// sources added after the refresh use the new cache right away.
+	if got := sources.add(&corev1.ConfigMap{}).cache; got != newCache {
+		t.Errorf("source added after refresh uses the old cache")
+	}
+}
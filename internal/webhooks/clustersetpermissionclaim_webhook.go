@@ -0,0 +1,120 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Paths the ClusterSetPermissionClaim webhooks are served under.
+const (
+	ClusterSetPermissionClaimValidatingPath = "/validate-permissions-thetechnick-ninja-v1alpha1-clustersetpermissionclaim"
+	ClusterSetPermissionClaimMutatingPath   = "/mutate-permissions-thetechnick-ninja-v1alpha1-clustersetpermissionclaim"
+)
+
+// ClusterSetPermissionClaimValidator validates ClusterSetPermissionClaims on create and update.
+// The template is validated like a PermissionClaim,
+// so invalid specs are rejected before PermissionClaims are created from them.
+type ClusterSetPermissionClaimValidator struct {
+	claimValidator *PermissionClaimValidator
+	decoder        *admission.Decoder
+}
+
+var _ admission.Handler = (*ClusterSetPermissionClaimValidator)(nil)
+
+func NewClusterSetPermissionClaimValidator(
+	claimValidator *PermissionClaimValidator,
+) *ClusterSetPermissionClaimValidator {
+	return &ClusterSetPermissionClaimValidator{
+		claimValidator: claimValidator,
+	}
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *ClusterSetPermissionClaimValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *ClusterSetPermissionClaimValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	set := &permissionsv1alpha1.ClusterSetPermissionClaim{}
+	if err := v.decoder.Decode(req, set); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if _, err := metav1.LabelSelectorAsSelector(&set.Spec.TargetClusterSelector); err != nil {
+		allErrs = append(allErrs, field.Invalid(
+			specPath.Child("targetClusterSelector"), set.Spec.TargetClusterSelector, err.Error()))
+	}
+	templatePath := specPath.Child("template")
+	if set.Spec.Template.TargetClusterRef != nil {
+		allErrs = append(allErrs, field.Forbidden(templatePath.Child("targetClusterRef"),
+			"TargetClusters are selected via .spec.targetClusterSelector"))
+	}
+	allErrs = append(allErrs, v.claimValidator.validateSpec(templatePath, &set.Spec.Template)...)
+	if len(allErrs) > 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	claim := &permissionsv1alpha1.PermissionClaim{Spec: set.Spec.Template}
+	var warnings []string
+	for _, warning := range v.claimValidator.riskWarnings(claim) {
+		warnings = append(warnings, "spec.template."+strings.TrimPrefix(warning, "spec."))
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// ClusterSetPermissionClaimRequesterAnnotator records the user who last changed
+// the spec of a ClusterSetPermissionClaim in the requester annotation.
+// The operator copies it to the PermissionClaims created for the set.
+type ClusterSetPermissionClaimRequesterAnnotator struct {
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = (*ClusterSetPermissionClaimRequesterAnnotator)(nil)
+
+func NewClusterSetPermissionClaimRequesterAnnotator() *ClusterSetPermissionClaimRequesterAnnotator {
+	return &ClusterSetPermissionClaimRequesterAnnotator{}
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (a *ClusterSetPermissionClaimRequesterAnnotator) InjectDecoder(d *admission.Decoder) error {
+	a.decoder = d
+	return nil
+}
+
+func (a *ClusterSetPermissionClaimRequesterAnnotator) Handle(
+	ctx context.Context, req admission.Request,
+) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	set := &permissionsv1alpha1.ClusterSetPermissionClaim{}
+	if err := a.decoder.Decode(req, set); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var oldSet *permissionsv1alpha1.ClusterSetPermissionClaim
+	if req.Operation == admissionv1.Update {
+		oldSet = &permissionsv1alpha1.ClusterSetPermissionClaim{}
+		if err := a.decoder.DecodeRaw(req.OldObject, oldSet); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	return annotateUser(req, set, oldSet, permissionsv1alpha1.RequesterAnnotation, oldSet != nil &&
+		equality.Semantic.DeepEqual(oldSet.Spec, set.Spec))
+}
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Path the PermissionClaim mutating webhook is served under.
+const PermissionClaimMutatingPath = "/mutate-permissions-thetechnick-ninja-v1alpha1-permissionclaim"
+
+// PermissionClaimRequesterAnnotator records the user who last changed
+// the spec of a PermissionClaim in the requester annotation.
+type PermissionClaimRequesterAnnotator struct {
+	// Username of the operator ServiceAccount.
+	// The operator presets the requester on PermissionClaims it creates for ClusterSetPermissionClaims.
+	OperatorUsername string
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = (*PermissionClaimRequesterAnnotator)(nil)
+
+func NewPermissionClaimRequesterAnnotator(operatorUsername string) *PermissionClaimRequesterAnnotator {
+	return &PermissionClaimRequesterAnnotator{
+		OperatorUsername: operatorUsername,
+	}
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (a *PermissionClaimRequesterAnnotator) InjectDecoder(d *admission.Decoder) error {
+	a.decoder = d
+	return nil
+}
+
+func (a *PermissionClaimRequesterAnnotator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	claim := &permissionsv1alpha1.PermissionClaim{}
+	if err := a.decoder.Decode(req, claim); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if len(a.OperatorUsername) > 0 && req.UserInfo.Username == a.OperatorUsername {
+		// Requester copied from the ClusterSetPermissionClaim by the operator,
+		// which would otherwise be recorded as requester itself.
+		return admission.Allowed("")
+	}
+
+	var oldClaim *permissionsv1alpha1.PermissionClaim
+	if req.Operation == admissionv1.Update {
+		oldClaim = &permissionsv1alpha1.PermissionClaim{}
+		if err := a.decoder.DecodeRaw(req.OldObject, oldClaim); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	return annotateUser(req, claim, oldClaim, permissionsv1alpha1.RequesterAnnotation, oldClaim != nil &&
+		equality.Semantic.DeepEqual(oldClaim.Spec, claim.Spec))
+}
+
+// annotateUser records the user of the request in the given annotation of obj.
+// If specUnchanged is set, the user recorded on oldObj is kept instead,
+// so updates that leave the spec alone, e.g. adding finalizers, do not replace the user.
+// This also prevents the annotation from being tampered with.
+func annotateUser(
+	req admission.Request, obj, oldObj client.Object, key string, specUnchanged bool,
+) admission.Response {
+	user, err := json.Marshal(req.UserInfo)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	annotation := string(user)
+	if specUnchanged {
+		annotation = oldObj.GetAnnotations()[key]
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if len(annotation) == 0 {
+		delete(annotations, key)
+	} else {
+		annotations[key] = annotation
+	}
+	obj.SetAnnotations(annotations)
+
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
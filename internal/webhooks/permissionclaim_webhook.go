@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	"github.com/thetechnick/permission-claim-operator/internal/escalation"
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Path the PermissionClaim validating webhook is served under.
+const PermissionClaimValidatingPath = "/validate-permissions-thetechnick-ninja-v1alpha1-permissionclaim"
+
+// PermissionClaimValidator validates PermissionClaims on create and update.
+type PermissionClaimValidator struct {
+	// Rejects rules using "*" in verbs, apiGroups, resources or nonResourceURLs.
+	DisallowWildcards bool
+	// Findings of the analyzer are returned as warnings.
+	RiskAnalyzer *escalation.Analyzer
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = (*PermissionClaimValidator)(nil)
+
+func NewPermissionClaimValidator(
+	disallowWildcards bool, riskAnalyzer *escalation.Analyzer,
+) *PermissionClaimValidator {
+	return &PermissionClaimValidator{
+		DisallowWildcards: disallowWildcards,
+		RiskAnalyzer:      riskAnalyzer,
+	}
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *PermissionClaimValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *PermissionClaimValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	claim := &permissionsv1alpha1.PermissionClaim{}
+	if err := v.decoder.Decode(req, claim); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	allErrs := v.validateSpec(field.NewPath("spec"), &claim.Spec)
+	if req.Operation == admissionv1.Update {
+		oldClaim := &permissionsv1alpha1.PermissionClaim{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldClaim); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		allErrs = append(allErrs, validateUpdate(claim, oldClaim)...)
+	}
+	if len(allErrs) > 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	return admission.Allowed("").WithWarnings(v.riskWarnings(claim)...)
+}
+
+// validateSpec validates the given PermissionClaimSpec located at specPath.
+func (v *PermissionClaimValidator) validateSpec(
+	specPath *field.Path, spec *permissionsv1alpha1.PermissionClaimSpec,
+) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateNamespaceName(specPath.Child("namespace"), spec.Namespace)...)
+	for i, ns := range spec.Namespaces {
+		allErrs = append(allErrs, validateNamespaceName(specPath.Child("namespaces").Index(i), ns)...)
+	}
+	if spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(
+				specPath.Child("namespaceSelector"), spec.NamespaceSelector, err.Error()))
+		}
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(spec.SecretName) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("secretName"), spec.SecretName, msg))
+	}
+	if spec.TargetClusterRef != nil {
+		namePath := specPath.Child("targetClusterRef", "name")
+		for _, msg := range validation.IsDNS1123Subdomain(spec.TargetClusterRef.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, spec.TargetClusterRef.Name, msg))
+		}
+	}
+
+	for i, rule := range spec.Rules {
+		allErrs = append(allErrs, v.validateRule(specPath.Child("rules").Index(i), rule, true)...)
+	}
+	for i, nsRules := range spec.NamespacedRules {
+		nsRulesPath := specPath.Child("namespacedRules").Index(i)
+		allErrs = append(allErrs, validateNamespaceName(nsRulesPath.Child("namespace"), nsRules.Namespace)...)
+		for j, rule := range nsRules.Rules {
+			allErrs = append(allErrs, v.validateRule(nsRulesPath.Child("rules").Index(j), rule, true)...)
+		}
+	}
+	for i, rule := range spec.ClusterRules {
+		allErrs = append(allErrs, v.validateRule(specPath.Child("clusterRules").Index(i), rule, false)...)
+	}
+	return allErrs
+}
+
+func (v *PermissionClaimValidator) validateRule(
+	path *field.Path, rule rbacv1.PolicyRule, namespaced bool,
+) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(rule.Verbs) == 0 {
+		allErrs = append(allErrs, field.Required(path.Child("verbs"), "at least one verb is required"))
+	}
+
+	if len(rule.NonResourceURLs) > 0 {
+		if namespaced {
+			allErrs = append(allErrs, field.Forbidden(path.Child("nonResourceURLs"),
+				"namespace-scoped rules cannot apply to non-resource URLs"))
+		}
+		if len(rule.APIGroups) > 0 || len(rule.Resources) > 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("nonResourceURLs"), rule.NonResourceURLs,
+				"rules cannot apply to both regular resources and non-resource URLs"))
+		}
+	} else {
+		if len(rule.APIGroups) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("apiGroups"),
+				"resource rules must supply at least one api group"))
+		}
+		if len(rule.Resources) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("resources"),
+				"resource rules must supply at least one resource"))
+		}
+	}
+
+	if v.DisallowWildcards {
+		for _, f := range []struct {
+			name   string
+			values []string
+		}{
+			{"verbs", rule.Verbs},
+			{"apiGroups", rule.APIGroups},
+			{"resources", rule.Resources},
+			{"nonResourceURLs", rule.NonResourceURLs},
+		} {
+			for i, value := range f.values {
+				if value == "*" {
+					allErrs = append(allErrs, field.Forbidden(path.Child(f.name).Index(i), "wildcards are not allowed"))
+				}
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateUpdate(claim, oldClaim *permissionsv1alpha1.PermissionClaim) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	// Objects already created on the previous target cluster would be orphaned.
+	if !equality.Semantic.DeepEqual(claim.Spec.TargetClusterRef, oldClaim.Spec.TargetClusterRef) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("targetClusterRef"),
+			"field is immutable"))
+	}
+
+	if !meta.IsStatusConditionTrue(oldClaim.Status.Conditions, permissionsv1alpha1.PermissionClaimBound) {
+		return allErrs
+	}
+	if claim.Spec.Namespace != oldClaim.Spec.Namespace {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("namespace"),
+			"field is immutable once the claim is bound"))
+	}
+	if claim.Spec.SecretName != oldClaim.Spec.SecretName {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("secretName"),
+			"field is immutable once the claim is bound"))
+	}
+	return allErrs
+}
+
+func validateNamespaceName(path *field.Path, namespace string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(namespace) {
+		allErrs = append(allErrs, field.Invalid(path, namespace, msg))
+	}
+	return allErrs
+}
+
+// returns a warning for every privilege escalation path in the claim.
+func (v *PermissionClaimValidator) riskWarnings(claim *permissionsv1alpha1.PermissionClaim) []string {
+	var warnings []string
+	for _, f := range v.RiskAnalyzer.Analyze(claim).Findings {
+		warnings = append(warnings, fmt.Sprintf(
+			"%s: %s risk of privilege escalation (%s), %s", f.Field, f.Severity, f.Type, f.Message))
+	}
+	return warnings
+}
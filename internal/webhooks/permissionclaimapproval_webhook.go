@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	permissionsv1alpha1 "github.com/thetechnick/permission-claim-operator/apis/permissions/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Path the PermissionClaimApproval mutating webhook is served under.
+const PermissionClaimApprovalMutatingPath = "/mutate-permissions-thetechnick-ninja-v1alpha1-permissionclaimapproval"
+
+// PermissionClaimApprovalApproverAnnotator records the user who created
+// a PermissionClaimApproval or last changed its spec in the approver annotation.
+// The operator ignores approvals by the requester of the PermissionClaim.
+type PermissionClaimApprovalApproverAnnotator struct {
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = (*PermissionClaimApprovalApproverAnnotator)(nil)
+
+func NewPermissionClaimApprovalApproverAnnotator() *PermissionClaimApprovalApproverAnnotator {
+	return &PermissionClaimApprovalApproverAnnotator{}
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (a *PermissionClaimApprovalApproverAnnotator) InjectDecoder(d *admission.Decoder) error {
+	a.decoder = d
+	return nil
+}
+
+func (a *PermissionClaimApprovalApproverAnnotator) Handle(
+	ctx context.Context, req admission.Request,
+) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	approval := &permissionsv1alpha1.PermissionClaimApproval{}
+	if err := a.decoder.Decode(req, approval); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var oldApproval *permissionsv1alpha1.PermissionClaimApproval
+	if req.Operation == admissionv1.Update {
+		oldApproval = &permissionsv1alpha1.PermissionClaimApproval{}
+		if err := a.decoder.DecodeRaw(req.OldObject, oldApproval); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	return annotateUser(req, approval, oldApproval, permissionsv1alpha1.ApproverAnnotation, oldApproval != nil &&
+		equality.Semantic.DeepEqual(oldApproval.Spec, approval.Spec))
+}